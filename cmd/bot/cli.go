@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"scroll-rank-bot/internal/coingecko"
+	"scroll-rank-bot/internal/gas"
+	"scroll-rank-bot/internal/market"
+	"scroll-rank-bot/internal/report"
+)
+
+// runRank prints rankings to stdout, once or every coin_data_update_interval
+func runRank(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("rank", flag.ExitOnError)
+	once := flags.Bool("once", false, "print the rankings once and exit")
+	format := flags.String("format", "text", "output format: text, json or csv")
+	flags.Parse(args)
+
+	switch *format {
+	case "text", "json", "csv":
+	default:
+		return fmt.Errorf("unknown format %q, want text, json or csv", *format)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	discoverSymbols(ctx, cfg)
+
+	aggregator := market.NewAggregator(coingecko.NewClient(cfg.HTTPTimeout, cfg.RetryPolicy()), market.OptionsFromConfig(cfg))
+	coins := cfg.CoinList()
+
+	// A single run is over before the streams deliver anything
+	if !*once {
+		aggregator.StartStreaming(ctx)
+	}
+
+	return repeat(ctx, *once, cfg.CoinDataUpdateInterval, func() error {
+		fetchCtx, cancel := context.WithTimeout(ctx, cfg.CoinDataUpdateTimeout)
+		results := aggregator.Reprice(aggregator.FetchRankings(fetchCtx, coins))
+		cancel()
+
+		now := time.Now()
+		switch *format {
+		case "json":
+			return report.WriteRankingsJSON(os.Stdout, results, now)
+		case "csv":
+			return report.WriteRankingsCSV(os.Stdout, results)
+		default:
+			_, err := fmt.Fprintln(os.Stdout, report.Rankings(results, now))
+			return err
+		}
+	})
+}
+
+// runGas prints gas prices to stdout, once or every coin_data_update_interval
+func runGas(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("gas", flag.ExitOnError)
+	once := flags.Bool("once", false, "print gas prices once and exit")
+	format := flags.String("format", "text", "output format: text or json")
+	flags.Parse(args)
+
+	switch *format {
+	case "text", "json":
+	default:
+		return fmt.Errorf("unknown format %q, want text or json", *format)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	gasService := gas.NewPriceService(cfg.HTTPTimeout, cfg.GasNetworks, cfg.RetryPolicy())
+
+	return repeat(ctx, *once, cfg.CoinDataUpdateInterval, func() error {
+		fetchCtx, cancel := context.WithTimeout(ctx, cfg.CoinDataUpdateTimeout)
+		prices := gasService.FetchAllPrices(fetchCtx)
+		cancel()
+
+		now := time.Now()
+		if *format == "json" {
+			return report.WriteGasPricesJSON(os.Stdout, gasService.Networks(), prices, now)
+		}
+		_, err := fmt.Fprintln(os.Stdout, report.GasPrices(gasService.Networks(), prices, now))
+		return err
+	})
+}
+
+// repeat runs fn once, then every interval until ctx is canceled unless once is set
+func repeat(ctx context.Context, once bool, interval time.Duration, fn func() error) error {
+	if err := fn(); err != nil || once {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := fn(); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -1,23 +1,139 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"scroll-rank-bot/internal/bot"
+	"scroll-rank-bot/internal/config"
+	"scroll-rank-bot/internal/health"
+	"scroll-rank-bot/internal/logging"
 
 	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+const usage = `Usage: bot [command] [flags]
+
+Commands:
+  serve   Run the Telegram bot (default)
+  rank    Print L2 rankings to stdout without Telegram
+  gas     Print gas prices to stdout without Telegram
+  symbols Check configured exchange symbols against exchange listings
+
+Run "bot <command> -h" for command flags.
+`
+
 func main() {
-	if err := godotenv.Load(); err != nil {
-		log.Fatal("Error loading .env file")
+	// .env is optional; variables may come from the real environment instead
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		fatal("Error loading .env file", err)
+	}
+
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var err error
+	switch command {
+	case "serve":
+		// Catch SIGHUP before any setup; its default action would kill the bot
+		// until the config watcher is running
+		sighup := make(chan os.Signal, 1)
+		signal.Notify(sighup, syscall.SIGHUP)
+		defer signal.Stop(sighup)
+		err = runServe(ctx, sighup)
+	case "rank":
+		err = runRank(ctx, args)
+	case "gas":
+		err = runGas(ctx, args)
+	case "symbols":
+		err = runSymbols(ctx, args)
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fatal("Exiting", err)
+	}
+}
+
+// fatal logs err and exits with a non-zero status
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// loadConfig loads the config and installs the configured logger
+func loadConfig() (*config.Config, error) {
+	cfg, err := config.Load(configPath())
+	if err != nil {
+		return nil, err
+	}
+	if err := logging.Setup(cfg.Log.Level, cfg.Log.Format); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// configPath returns the config file location, CONFIG_FILE or config.yaml
+func configPath() string {
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		return path
 	}
+	return "config.yaml"
+}
 
-	bot, err := bot.New(os.Getenv("TELEGRAM_BOT_TOKEN"))
+func runServe(ctx context.Context, sighup <-chan os.Signal) error {
+	cfg, err := loadConfig()
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+
+	token := os.Getenv("TELEGRAM_BOT_TOKEN")
+	if token == "" {
+		return errors.New("TELEGRAM_BOT_TOKEN is not set")
 	}
 
-	bot.Start()
+	discoverSymbols(ctx, cfg)
+
+	bot, err := bot.New(token, cfg)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Monitoring.ListenAddr != "" {
+		listener, err := net.Listen("tcp", cfg.Monitoring.ListenAddr)
+		if err != nil {
+			return fmt.Errorf("failed to listen for monitoring: %w", err)
+		}
+		mux := http.NewServeMux()
+		health.Register(mux, bot)
+		mux.Handle("/metrics", promhttp.Handler())
+		go serveMonitoring(ctx, listener, mux)
+	}
+
+	bot.Go(func() {
+		config.Watch(ctx, configPath(), cfg.SymbolsFile, cfg.ReloadCheckInterval, sighup, func(ctx context.Context, cfg *config.Config) {
+			discoverSymbols(ctx, cfg)
+			bot.Reload(ctx, cfg)
+		})
+	})
+
+	return bot.Start(ctx)
 }
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// serveMonitoring serves handler on the bound listener until ctx is canceled
+func serveMonitoring(ctx context.Context, listener net.Listener, handler http.Handler) {
+	server := &http.Server{Handler: handler}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("Monitoring server listening", "addr", listener.Addr().String())
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("Monitoring server failed", "error", err)
+	}
+}
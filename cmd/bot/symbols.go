@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"scroll-rank-bot/internal/config"
+	"scroll-rank-bot/internal/discovery"
+	"scroll-rank-bot/internal/exchanges"
+)
+
+// discoverSymbols checks the configured symbols against exchange listings, logging
+// problems and filling in discovered symbols if configured. It's a no-op when
+// symbol discovery is disabled.
+func discoverSymbols(ctx context.Context, cfg *config.Config) {
+	if !cfg.SymbolDiscovery.Enabled {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.CoinDataUpdateTimeout)
+	defer cancel()
+
+	findings := discovery.Check(ctx, exchanges.NewProviders(cfg.HTTPTimeout), cfg.Coins, cfg.SymbolDiscovery.Quote)
+	discovery.Log(findings)
+	if cfg.SymbolDiscovery.Fill {
+		discovery.Fill(cfg, findings)
+	}
+}
+
+// runSymbols prints configured and discovered exchange symbols for every coin
+func runSymbols(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("symbols", flag.ExitOnError)
+	flags.Parse(args)
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.CoinDataUpdateTimeout)
+	defer cancel()
+
+	findings := discovery.Check(ctx, exchanges.NewProviders(cfg.HTTPTimeout), cfg.Coins, cfg.SymbolDiscovery.Quote)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COIN\tEXCHANGE\tCONFIGURED\tLISTED\tSTATUS")
+	for _, f := range findings {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", f.Coin, f.Exchange, orDash(f.Configured), orDash(f.Listed), f.Status)
+	}
+	return w.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -1,264 +1,269 @@
 package bot
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"sort"
-	"strings"
+	"log/slog"
+	"net"
 	"sync"
 	"time"
 
 	"scroll-rank-bot/internal/coingecko"
+	"scroll-rank-bot/internal/config"
 	"scroll-rank-bot/internal/gas"
 	"scroll-rank-bot/internal/market"
+	"scroll-rank-bot/internal/metrics"
 	"scroll-rank-bot/internal/models"
+	"scroll-rank-bot/internal/report"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 type Bot struct {
 	api   *tgbotapi.BotAPI
-	coins map[string]models.Coin
+	coins []models.Coin
 	mutex sync.RWMutex
 
 	aggregator             *market.Aggregator
+	updateMutex            sync.Mutex // serializes updateCoinData cycles
 	coinDataUpdateInterval time.Duration
+	coinDataUpdateTimeout  time.Duration
 	lastCoingeckoTime      time.Time
 	cachedCoinDataRespMsg  string
+	cachedResults          []models.CoinResult // rankings of the last update cycle, repriced from ticker streams by /rank
+	streaming              bool
+	unavailableCoins       []string // coin IDs with no data in the last update cycle
+	staleCoins             []string // coin IDs served from last good data in the last update cycle
+	maxDataAge             time.Duration
 
 	gasService *gas.PriceService
+
+	telegram config.TelegramConfig
+
+	// wg tracks background work that must finish before shutdown completes
+	wg              sync.WaitGroup
+	shutdownTimeout time.Duration
 	// gasCacheDur time.Duration
 	// lastGasTime time.Time
 	// cachedGas   string
 }
 
-func New(token string) (*Bot, error) {
+func New(token string, cfg *config.Config) (*Bot, error) {
 	api, err := tgbotapi.NewBotAPI(token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create bot: %w", err)
 	}
 
 	// Create CoinGecko client
-	cgClient := coingecko.NewClient()
+	cgClient := coingecko.NewClient(cfg.HTTPTimeout, cfg.RetryPolicy())
 
 	// Create aggregator with providers and TTLs
-	httpTimeout := 10 * time.Second
-	supplyTTL := 24 * time.Hour
-	volumeTTL := 30 * time.Minute
-	aggregator := market.NewAggregator(cgClient, httpTimeout, supplyTTL, volumeTTL)
+	aggregator := market.NewAggregator(cgClient, market.OptionsFromConfig(cfg))
 
 	return &Bot{
 		api:                    api,
 		aggregator:             aggregator,
-		gasService:             gas.NewPriceService(),
-		coinDataUpdateInterval: 5 * time.Minute,
+		gasService:             gas.NewPriceService(cfg.HTTPTimeout, cfg.GasNetworks, cfg.RetryPolicy()),
+		coinDataUpdateInterval: cfg.CoinDataUpdateInterval,
+		coinDataUpdateTimeout:  cfg.CoinDataUpdateTimeout,
+		shutdownTimeout:        cfg.ShutdownTimeout,
+		maxDataAge:             cfg.Monitoring.MaxDataAge,
+		streaming:              cfg.Streaming.Enabled,
 		// gasCacheDur:            1 * time.Minute,
-		coins: map[string]models.Coin{
-			"starknet":  {Name: "Starknet", ID: "starknet"},
-			"zksync":    {Name: "ZkSync", ID: "zksync"},
-			"taiko":     {Name: "Taiko", ID: "taiko"},
-			"scroll":    {Name: "Scroll", ID: "scroll"},
-			"movement":  {Name: "Movement", ID: "movement"},
-			"polyhedra": {Name: "Polyhedra", ID: "polyhedra-network"},
-			"linea":     {Name: "Linea", ID: "linea"},
-		},
+		coins:    cfg.CoinList(),
+		telegram: cfg.Telegram,
 	}, nil
 }
 
-func (b *Bot) Start() {
-	log.Printf("Authorized on account %s", b.api.Self.UserName)
-
-	b.updateCoinData()
-	go b.startUpdateCoindataTicker()
+// Start serves Telegram updates until ctx is canceled, then shuts down gracefully.
+// Updates come from long polling or a webhook listener depending on the config.
+func (b *Bot) Start(ctx context.Context) error {
+	slog.Info("Authorized on account", "account", b.api.Self.UserName)
+
+	// Bind the webhook listener before anything else, so a taken port fails startup
+	// instead of leaving Telegram posting to a dead endpoint
+	var listener net.Listener
+	if b.telegram.Mode == config.TelegramModeWebhook {
+		var err error
+		listener, err = net.Listen("tcp", b.telegram.Webhook.ListenAddr)
+		if err != nil {
+			return fmt.Errorf("failed to listen for webhook: %w", err)
+		}
+	}
 
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
+	b.aggregator.StartStreaming(ctx)
+	b.updateCoinData(ctx)
 
-	updates := b.api.GetUpdatesChan(u)
-	b.handleUpdates(updates)
-}
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		b.startUpdateCoindataTicker(ctx)
+	}()
 
-func (b *Bot) handleUpdates(updates tgbotapi.UpdatesChannel) {
-	for update := range updates {
-		if update.Message == nil {
-			continue
+	var updates tgbotapi.UpdatesChannel
+	if b.telegram.Mode == config.TelegramModeWebhook {
+		var err error
+		updates, err = b.startWebhook(ctx, listener, b.telegram.Webhook)
+		if err != nil {
+			return err
 		}
-
-		switch update.Message.Command() {
-		case "rank":
-			b.mutex.RLock()
-			msg := tgbotapi.NewMessage(update.Message.Chat.ID, b.cachedCoinDataRespMsg)
-			b.mutex.RUnlock()
-			b.api.Send(msg)
-
-		case "gas_price":
-			gasPrices := b.gasService.FetchAllPrices()
-			msg := tgbotapi.NewMessage(update.Message.Chat.ID, b.formatGasPrices(gasPrices))
-			b.api.Send(msg)
+	} else {
+		// A webhook left over from a previous deployment would make getUpdates fail
+		if _, err := b.api.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+			return fmt.Errorf("failed to delete webhook: %w", err)
 		}
+
+		u := tgbotapi.NewUpdate(0)
+		u.Timeout = 60
+		updates = b.api.GetUpdatesChan(u)
 	}
+
+	b.handleUpdates(ctx, updates)
+	b.shutdown()
+	return nil
 }
 
-func (b *Bot) startUpdateCoindataTicker() {
-	ticker := time.NewTicker(b.coinDataUpdateInterval)
-	for range ticker.C {
-		b.updateCoinData()
+// shutdown stops receiving updates and waits, up to shutdownTimeout, for background work to finish
+func (b *Bot) shutdown() {
+	slog.Info("Shutting down, waiting for in-flight work", "timeout", b.shutdownTimeout)
+	b.api.StopReceivingUpdates()
+
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		slog.Info("Shutdown complete")
+	case <-time.After(b.shutdownTimeout):
+		slog.Warn("Shutdown timed out, abandoning in-flight work", "timeout", b.shutdownTimeout)
 	}
 }
 
-func (b *Bot) updateCoinData() {
-	var wg sync.WaitGroup
-	results := make(chan struct {
-		id   string
-		data *models.CoinData
-	}, len(b.coins))
-
-	for _, coin := range b.coins {
-		wg.Add(1)
-		go func(coin models.Coin) {
-			defer wg.Done()
-			data, err := b.aggregator.FetchCoinData(coin)
-			if err != nil {
-				log.Printf("Error fetching data for %s: %v", coin.ID, err)
-				results <- struct {
-					id   string
-					data *models.CoinData
-				}{id: coin.ID, data: nil}
+func (b *Bot) handleUpdates(ctx context.Context, updates tgbotapi.UpdatesChannel) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case update, ok := <-updates:
+			if !ok {
 				return
 			}
-			results <- struct {
-				id   string
-				data *models.CoinData
-			}{id: coin.ID, data: data}
-		}(coin)
+			b.handleUpdate(ctx, update)
+		}
 	}
+}
 
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
+func (b *Bot) handleUpdate(ctx context.Context, update tgbotapi.Update) {
+	if update.Message == nil {
+		return
+	}
 
-	var coinDataList []struct {
-		id   string
-		data *models.CoinData
+	switch update.Message.Command() {
+	case "rank":
+		msg := tgbotapi.NewMessage(update.Message.Chat.ID, b.rankingsMessage(time.Now()))
+		b.api.Send(msg)
+
+	case "gas_price":
+		gasCtx, cancel := context.WithTimeout(ctx, b.coinDataUpdateTimeout)
+		gasPrices := b.gasService.FetchAllPrices(gasCtx)
+		cancel()
+		msg := tgbotapi.NewMessage(update.Message.Chat.ID, report.GasPrices(b.gasService.Networks(), gasPrices, time.Now()))
+		b.api.Send(msg)
+
+	case "status":
+		msg := tgbotapi.NewMessage(update.Message.Chat.ID, b.statusMessage(time.Now()))
+		b.api.Send(msg)
 	}
-	for result := range results {
-		coinDataList = append(coinDataList, result)
+}
+
+// rankingsMessage returns the /rank text: the last update cycle's rankings, with
+// live prices from the ticker streams when streaming is enabled
+func (b *Bot) rankingsMessage(now time.Time) string {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	if !b.streaming {
+		return b.cachedCoinDataRespMsg
 	}
+	return report.Rankings(b.aggregator.Reprice(b.cachedResults), now)
+}
 
-	sort.Slice(coinDataList, func(i, j int) bool {
-		if coinDataList[i].data == nil || coinDataList[j].data == nil {
-			return false
+func (b *Bot) startUpdateCoindataTicker(ctx context.Context) {
+	ticker := time.NewTicker(b.coinDataUpdateInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.updateCoinData(ctx)
 		}
-		return coinDataList[i].data.FullyDilutedValuation.USD > coinDataList[j].data.FullyDilutedValuation.USD
-	})
+	}
+}
 
+// Reload swaps in the coin list, exchange symbols and order, and gas networks from cfg
+// and refreshes the cached rankings right away. Other settings require a restart.
+func (b *Bot) Reload(ctx context.Context, cfg *config.Config) {
 	b.mutex.Lock()
-	b.cachedCoinDataRespMsg = b.formatCoinData(coinDataList)
-	b.lastCoingeckoTime = time.Now()
+	b.coins = cfg.CoinList()
+	b.aggregator.SetExchanges(cfg.Symbols(), cfg.ExchangeOrder())
+	b.gasService.SetNetworks(cfg.GasNetworks)
 	b.mutex.Unlock()
 
-	log.Printf("Data updated successfully at %v", time.Now())
-}
+	slog.Info("Config reloaded", "coins", len(cfg.Coins), "gas_networks", len(cfg.GasNetworks))
 
-func (b *Bot) formatCoinData(data []struct {
-	id   string
-	data *models.CoinData
-}) string {
-	var messages []string
-
-	// Add a header line with emojis
-	header := "🏆 L2 RANKINGS BY FDV 🏆"
-
-	for i, item := range data {
-		// Add ranking number for each coin
-		messages = append(messages, b.formatSingleCoin(i+1, item.id, item.data))
-	}
-
-	// More compact date format
-	timestamp := time.Now().UTC().Format("2006-01-02 15:04 UTC")
+	b.updateCoinData(ctx)
+}
 
-	return fmt.Sprintf("%s\n\n\n%s\n\n\n📊 Updated: %s",
-		header,
-		strings.Join(messages, "\n\n"),
-		timestamp)
+// Go runs fn in a goroutine that shutdown waits for. It must be called before
+// Start, so the wait group is never added to while shutdown waits on it.
+func (b *Bot) Go(fn func()) {
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		fn()
+	}()
 }
 
-func (b *Bot) formatSingleCoin(rank int, coinID string, data *models.CoinData) string {
-	if data == nil {
-		return fmt.Sprintf("#%d %s: Data unavailable", rank, coinID)
-	}
+// updateCoinData refreshes the cached rankings; fetches are bounded by coinDataUpdateTimeout
+func (b *Bot) updateCoinData(ctx context.Context) {
+	b.updateMutex.Lock()
+	defer b.updateMutex.Unlock()
 
-	// Determine emoji based on rank
-	rankEmoji := ""
-	switch rank {
-	case 1:
-		rankEmoji = "🥇"
-	case 2:
-		rankEmoji = "🥈"
-	case 3:
-		rankEmoji = "🥉"
-	default:
-		rankEmoji = "▫️"
-	}
+	ctx, cancel := context.WithTimeout(ctx, b.coinDataUpdateTimeout)
+	defer cancel()
 
-	// Set price change arrow and color indicator (using emoji)
-	priceChangeIndicator := "➖"
-	if data.PriceChangePercentage24h > 0 {
-		priceChangeIndicator = "🟢"
-	} else if data.PriceChangePercentage24h < 0 {
-		priceChangeIndicator = "🔴"
-	}
+	start := time.Now()
 
-	// Remove "-network" suffix if present and convert to title case
-	displayName := strings.Title(coinID)
-	displayName = strings.TrimSuffix(displayName, "-Network")
-
-	// More compact single-line format per coin
-	return fmt.Sprintf(`%s #%d %s | 💰 %s (%s%.2f%%) | 📈 Vol: %s | 💎 MC: %s | 🌐 FDV: %s`,
-		rankEmoji,
-		rank,
-		displayName,
-		formatPrice(data.Price.USD),
-		priceChangeIndicator,
-		data.PriceChangePercentage24h,
-		formatValue(data.Volume24h.USD),
-		formatValue(data.MarketCap.USD),
-		formatValue(data.FullyDilutedValuation.USD))
-}
+	b.mutex.RLock()
+	coins := b.coins
+	b.mutex.RUnlock()
 
-func (b *Bot) formatGasPrices(prices map[string]float64) string {
-	return fmt.Sprintf(`🔄 Current Gas Prices (Gwei):
+	results := b.aggregator.FetchRankings(ctx, coins)
 
-⬙ Ethereum: %.2f
-⇆ ZkSync: %.2f
-▲ Taiko: %.2f
-📜 Scroll: %.2f
+	var unavailable, stale []string
+	for _, result := range results {
+		if result.Data == nil {
+			unavailable = append(unavailable, result.Coin.ID)
+		} else if result.Data.Stale {
+			stale = append(stale, result.Coin.ID)
+		}
+	}
 
-Updated: %s UTC`,
-		prices["ethereum"],
-		prices["zksync"],
-		prices["taiko"],
-		prices["scroll"],
-		time.Now().UTC().Format("2006-01-02 15:04:05"))
-}
+	metrics.UpdateCycleUnavailable.Set(float64(len(unavailable)))
 
-func formatValue(value float64) string {
-	if value == 0 {
-		return "N/A"
-	}
-	if value >= 1e9 {
-		return fmt.Sprintf("%.2f B", value/1e9)
-	}
-	if value >= 1e6 {
-		return fmt.Sprintf("%.2f M", value/1e6)
-	}
-	return fmt.Sprintf("%.2f", value)
-}
+	b.mutex.Lock()
+	b.cachedCoinDataRespMsg = report.Rankings(results, time.Now())
+	b.cachedResults = results
+	b.unavailableCoins = unavailable
+	b.staleCoins = stale
+	b.lastCoingeckoTime = time.Now()
+	b.mutex.Unlock()
 
-func formatPrice(price float64) string {
-	if price == 0 {
-		return "N/A"
-	}
-	return fmt.Sprintf("$%.4f", price)
+	metrics.UpdateCycleDuration.Observe(metrics.Since(start))
+	slog.Info("Data updated", "coins", len(results), "unavailable", len(unavailable), "stale", len(stale), "latency", time.Since(start))
 }
@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"scroll-rank-bot/internal/health"
+)
+
+// Readiness reports whether /rank is serving fresh, complete data
+func (b *Bot) Readiness() health.Report {
+	now := time.Now()
+
+	b.mutex.RLock()
+	lastUpdate := b.lastCoingeckoTime
+	unavailable := b.unavailableCoins
+	stale := b.staleCoins
+	coins := b.coins
+	b.mutex.RUnlock()
+
+	var report health.Report
+
+	freshness := health.Check{Name: "data_freshness"}
+	if lastUpdate.IsZero() {
+		freshness.Detail = "no update cycle has completed yet"
+	} else {
+		age := now.Sub(lastUpdate)
+		freshness.OK = age <= b.maxDataAge
+		freshness.Detail = fmt.Sprintf("last update %s ago (max %s)", age.Round(time.Second), b.maxDataAge)
+	}
+	report.Add(freshness)
+
+	// Without a valid snapshot, exchange fallback can't compute MC/FDV for the coin
+	snapshots := b.aggregator.SupplySnapshots()
+	supplyTTL := b.aggregator.SupplyTTL()
+	for _, coin := range coins {
+		check := health.Check{Name: "supply_snapshot:" + coin.ID}
+		snapshot, ok := snapshots[coin.ID]
+		if !ok {
+			check.Detail = "no snapshot cached"
+		} else {
+			age := now.Sub(snapshot.UpdatedAt)
+			check.OK = snapshot.ValidSupply(now, supplyTTL)
+			check.Detail = fmt.Sprintf("age %s (ttl %s)", age.Round(time.Second), supplyTTL)
+		}
+		report.Add(check)
+	}
+
+	// Last good data keeps /rank populated during an outage, but it isn't fresh
+	availability := health.Check{Name: "last_cycle_complete", OK: len(unavailable) == 0 && len(stale) == 0}
+	var problems []string
+	if len(unavailable) > 0 {
+		problems = append(problems, "data unavailable for "+strings.Join(unavailable, ", "))
+	}
+	if len(stale) > 0 {
+		problems = append(problems, "last good data served for "+strings.Join(stale, ", "))
+	}
+	availability.Detail = strings.Join(problems, "; ")
+	report.Add(availability)
+
+	return report
+}
@@ -0,0 +1,57 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"scroll-rank-bot/internal/metrics"
+)
+
+// statusMessage renders the /status reply: data freshness, upstream state and gaps
+func (b *Bot) statusMessage(now time.Time) string {
+	b.mutex.RLock()
+	lastUpdate := b.lastCoingeckoTime
+	unavailable := b.unavailableCoins
+	stale := b.staleCoins
+	b.mutex.RUnlock()
+
+	updated := "never"
+	if !lastUpdate.IsZero() {
+		updated = fmt.Sprintf("%s ago", now.Sub(lastUpdate).Round(time.Second))
+	}
+
+	return fmt.Sprintf("🩺 Bot Status\n\n📊 Last update: %s\n🦎 CoinGecko circuit: %s\n🏦 Exchanges: %s\n🕰 Last good: %s\n⚠️ Unavailable: %s",
+		updated,
+		b.aggregator.BreakerState(),
+		b.exchangeStatus(now),
+		coinList(stale),
+		coinList(unavailable))
+}
+
+// exchangeStatus summarizes each exchange as its last request outcome, or when it
+// may be called again if it's suspended
+func (b *Bot) exchangeStatus(now time.Time) string {
+	var parts []string
+	for _, status := range b.aggregator.ProviderStatuses() {
+		state := status.LastOutcome
+		switch {
+		case now.Before(status.SuspendedUntil):
+			state = "suspended until " + status.SuspendedUntil.UTC().Format("15:04:05") + " UTC"
+		case state == "":
+			state = "unused"
+		case state == metrics.OutcomeSuccess:
+			state = "ok"
+		}
+		parts = append(parts, status.Name+" "+state)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// coinList joins coin IDs for display, or "none"
+func coinList(ids []string) string {
+	if len(ids) == 0 {
+		return "none"
+	}
+	return strings.Join(ids, ", ")
+}
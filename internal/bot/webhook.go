@@ -0,0 +1,90 @@
+package bot
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"scroll-rank-bot/internal/config"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// secretTokenHeader carries the secret_token passed to setWebhook on every webhook request
+const secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// webhookHandler accepts Telegram webhook posts and forwards them to updates
+type webhookHandler struct {
+	secretToken string
+	updates     chan<- tgbotapi.Update
+}
+
+func (h *webhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := r.Header.Get(secretTokenHeader)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.secretToken)) != 1 {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	var update tgbotapi.Update
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case h.updates <- update:
+		w.WriteHeader(http.StatusOK)
+	case <-r.Context().Done():
+		// Telegram retries undelivered updates, so dropping here is safe
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}
+}
+
+// startWebhook registers the webhook with Telegram and serves it on listener,
+// which is already bound, until ctx is canceled
+func (b *Bot) startWebhook(ctx context.Context, listener net.Listener, cfg config.WebhookConfig) (tgbotapi.UpdatesChannel, error) {
+	params := tgbotapi.Params{}
+	params["url"] = cfg.URL
+	params["secret_token"] = cfg.SecretToken
+	if _, err := b.api.MakeRequest("setWebhook", params); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to set webhook: %w", err)
+	}
+
+	updates := make(chan tgbotapi.Update, b.api.Buffer)
+	mux := http.NewServeMux()
+	mux.Handle(cfg.Path, &webhookHandler{secretToken: cfg.SecretToken, updates: updates})
+	server := &http.Server{Handler: mux}
+
+	go func() {
+		slog.Info("Listening for webhook updates", "addr", listener.Addr().String(), "path", cfg.Path)
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Webhook server failed", "error", err)
+		}
+	}()
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), b.shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Warn("Webhook server shutdown", "error", err)
+		}
+	}()
+
+	return updates, nil
+}
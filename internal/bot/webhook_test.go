@@ -0,0 +1,95 @@
+package bot
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"scroll-rank-bot/internal/config"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const testSecret = "s3cret"
+
+func TestWebhookHandler(t *testing.T) {
+	const update = `{"update_id":42,"message":{"message_id":1,"date":1700000000,"chat":{"id":7,"type":"private"},"text":"/rank"}}`
+
+	tests := []struct {
+		name   string
+		method string
+		secret string
+		body   string
+		want   int
+	}{
+		{name: "missing secret", method: http.MethodPost, body: update, want: http.StatusForbidden},
+		{name: "wrong secret", method: http.MethodPost, secret: "wrong", body: update, want: http.StatusForbidden},
+		{name: "not a post", method: http.MethodGet, secret: testSecret, want: http.StatusMethodNotAllowed},
+		{name: "malformed body", method: http.MethodPost, secret: testSecret, body: `{"update_id":`, want: http.StatusBadRequest},
+		{name: "valid update", method: http.MethodPost, secret: testSecret, body: update, want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updates := make(chan tgbotapi.Update, 1)
+			server := httptest.NewServer(&webhookHandler{secretToken: testSecret, updates: updates})
+			defer server.Close()
+
+			req, err := http.NewRequest(tt.method, server.URL, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.secret != "" {
+				req.Header.Set(secretTokenHeader, tt.secret)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+
+			if tt.want != http.StatusOK {
+				if len(updates) != 0 {
+					t.Fatal("rejected request was forwarded as an update")
+				}
+				return
+			}
+
+			select {
+			case got := <-updates:
+				if got.UpdateID != 42 || got.Message == nil || got.Message.Text != "/rank" {
+					t.Fatalf("unexpected update %+v", got)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("update not delivered")
+			}
+		})
+	}
+}
+
+func TestStartFailsWhenWebhookAddrTaken(t *testing.T) {
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+
+	b := &Bot{
+		api: &tgbotapi.BotAPI{},
+		telegram: config.TelegramConfig{
+			Mode:    config.TelegramModeWebhook,
+			Webhook: config.WebhookConfig{ListenAddr: taken.Addr().String()},
+		},
+	}
+	if err := b.Start(context.Background()); err == nil {
+		t.Fatal("Start succeeded with listen_addr already in use")
+	}
+}
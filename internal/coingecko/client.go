@@ -1,38 +1,105 @@
 package coingecko
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
+	"scroll-rank-bot/internal/exchanges"
+	"scroll-rank-bot/internal/metrics"
 	"scroll-rank-bot/internal/models"
 )
 
+const baseURL = "https://api.coingecko.com/api/v3"
+
 type Client struct {
 	httpClient *http.Client
+	retry      exchanges.RetryPolicy
 }
 
-func NewClient() *Client {
+func NewClient(timeout time.Duration, retry exchanges.RetryPolicy) *Client {
 	return &Client{
 		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout: timeout,
 		},
+		retry: retry,
+	}
+}
+
+// marketEntry is one element of the /coins/markets response
+type marketEntry struct {
+	ID                       string  `json:"id"`
+	CurrentPrice             float64 `json:"current_price"`
+	MarketCap                float64 `json:"market_cap"`
+	FullyDilutedValuation    float64 `json:"fully_diluted_valuation"`
+	TotalVolume              float64 `json:"total_volume"`
+	PriceChangePercentage24h float64 `json:"price_change_percentage_24h"`
+}
+
+// FetchMarkets fetches USD market data for all coinIDs in a single /coins/markets request.
+// The result is keyed by coin ID; IDs CoinGecko doesn't know are simply absent.
+func (c *Client) FetchMarkets(ctx context.Context, coinIDs []string) (map[string]*models.CoinData, error) {
+	params := url.Values{}
+	params.Set("vs_currency", "usd")
+	params.Set("ids", strings.Join(coinIDs, ","))
+	params.Set("per_page", "250")
+
+	var entries []marketEntry
+	if err := c.getJSON(ctx, baseURL+"/coins/markets?"+params.Encode(), &entries); err != nil {
+		return nil, err
+	}
+
+	markets := make(map[string]*models.CoinData, len(entries))
+	for _, entry := range entries {
+		markets[entry.ID] = &models.CoinData{
+			Price:                    models.MultiCurrency{USD: entry.CurrentPrice},
+			PriceChangePercentage24h: entry.PriceChangePercentage24h,
+			MarketCap:                models.MultiCurrency{USD: entry.MarketCap},
+			FullyDilutedValuation:    models.MultiCurrency{USD: entry.FullyDilutedValuation},
+			Volume24h:                models.MultiCurrency{USD: entry.TotalVolume},
+		}
 	}
+	return markets, nil
 }
 
-func (c *Client) FetchCoinData(coinID string) (*models.CoinData, error) {
-	url := fmt.Sprintf("https://api.coingecko.com/api/v3/coins/%s", coinID)
-	resp, err := c.httpClient.Get(url)
+// getJSON performs a GET request, retrying transient failures, and decodes a
+// successful JSON response into out
+func (c *Client) getJSON(ctx context.Context, rawURL string, out any) error {
+	attempts, err := c.retry.Do(ctx, "coingecko", func(ctx context.Context) error {
+		return c.doGetJSON(ctx, rawURL, out)
+	})
+	metrics.Retries.WithLabelValues("coingecko").Add(float64(attempts - 1))
+	return err
+}
+
+func (c *Client) doGetJSON(ctx context.Context, rawURL string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch coin data: %w", err)
+		return fmt.Errorf("failed to fetch coin data: %w", err)
 	}
 	defer resp.Body.Close()
 
-	var geckoResp models.CoinGeckoResponse
-	if err := json.NewDecoder(resp.Body).Decode(&geckoResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return fmt.Errorf("coingecko: %w", exchanges.ErrRateLimited)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("coingecko: %w", &exchanges.HTTPError{StatusCode: resp.StatusCode, Body: string(body)})
 	}
 
-	return &geckoResp.MarketData, nil
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,518 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"scroll-rank-bot/internal/exchanges"
+	"scroll-rank-bot/internal/logging"
+	"scroll-rank-bot/internal/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Telegram update delivery modes
+const (
+	TelegramModePolling = "polling"
+	TelegramModeWebhook = "webhook"
+)
+
+// Config is the typed bot configuration loaded from a YAML or JSON file
+type Config struct {
+	Telegram TelegramConfig `yaml:"telegram"`
+	Log      LogConfig      `yaml:"log"`
+
+	HTTPTimeout            time.Duration `yaml:"http_timeout"`
+	CoinDataUpdateInterval time.Duration `yaml:"coin_data_update_interval"`
+	CoinDataUpdateTimeout  time.Duration `yaml:"coin_data_update_timeout"`
+	SupplyTTL              time.Duration `yaml:"supply_ttl"`
+	VolumeTTL              time.Duration `yaml:"volume_ttl"`
+
+	// LastGoodGrace is how long a coin's last successful data is shown (marked
+	// with its age) once every source fails; zero shows "Data unavailable" at once
+	LastGoodGrace time.Duration `yaml:"last_good_grace"`
+
+	// ReloadCheckInterval is how often the config file is checked for changes;
+	// zero disables file watching (SIGHUP still triggers a reload)
+	ReloadCheckInterval time.Duration `yaml:"reload_check_interval"`
+
+	// ShutdownTimeout bounds how long in-flight work may run after SIGINT/SIGTERM
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+
+	// SupplyCacheFile persists supply snapshots across restarts; empty disables persistence
+	SupplyCacheFile string `yaml:"supply_cache_file"`
+
+	// Exchanges lists the exchanges used for fallback pricing, in the order
+	// they're tried; coins may override it
+	Exchanges []string `yaml:"exchanges"`
+
+	SymbolDiscovery SymbolDiscoveryConfig `yaml:"symbol_discovery"`
+
+	// SymbolsFile optionally points to a JSON file whose symbol mappings
+	// override the ones configured per coin
+	SymbolsFile string `yaml:"symbols_file"`
+
+	Monitoring MonitoringConfig `yaml:"monitoring"`
+
+	CoinGeckoBreaker BreakerConfig  `yaml:"coingecko_breaker"`
+	Retry            RetryConfig    `yaml:"retry"`
+	Fallback         FallbackConfig `yaml:"fallback"`
+
+	Streaming StreamingConfig `yaml:"streaming"`
+
+	Coins       []CoinConfig        `yaml:"coins"`
+	GasNetworks []models.GasNetwork `yaml:"gas_networks"`
+}
+
+// TelegramConfig selects how the bot receives updates
+type TelegramConfig struct {
+	Mode    string        `yaml:"mode"` // "polling" or "webhook"
+	Webhook WebhookConfig `yaml:"webhook"`
+}
+
+// WebhookConfig configures webhook mode, typically behind a reverse proxy
+type WebhookConfig struct {
+	URL         string `yaml:"url"`          // Public URL registered with Telegram
+	ListenAddr  string `yaml:"listen_addr"`  // Local address the listener binds to
+	Path        string `yaml:"path"`         // Path the listener serves updates on
+	SecretToken string `yaml:"secret_token"` // Verified against X-Telegram-Bot-Api-Secret-Token
+}
+
+// LogConfig selects the log level and output format
+type LogConfig struct {
+	Level  string `yaml:"level"`  // debug, info, warn or error
+	Format string `yaml:"format"` // text or json
+}
+
+// MonitoringConfig configures the optional health/readiness HTTP server
+type MonitoringConfig struct {
+	ListenAddr string        `yaml:"listen_addr"`  // Empty disables the server
+	MaxDataAge time.Duration `yaml:"max_data_age"` // Rankings older than this make /readyz fail
+}
+
+// BreakerConfig configures a circuit breaker around an upstream
+type BreakerConfig struct {
+	FailureThreshold int           `yaml:"failure_threshold"` // Consecutive failures that open the circuit
+	Cooldown         time.Duration `yaml:"cooldown"`          // Time before a single probe request is allowed
+}
+
+// RetryConfig configures retries of transient upstream failures
+type RetryConfig struct {
+	MaxAttempts int           `yaml:"max_attempts"` // Total attempts including the first
+	BaseDelay   time.Duration `yaml:"base_delay"`   // Backoff ceiling for the first retry
+	MaxDelay    time.Duration `yaml:"max_delay"`    // Upper bound for the backoff ceiling
+}
+
+// FallbackConfig selects how exchange prices are combined when CoinGecko is unavailable
+type FallbackConfig struct {
+	// Mode is "sequential" (first exchange that answers), "hedged" (like
+	// sequential, but the next exchange starts after HedgeDelay without waiting)
+	// or "consensus" (median across all exchanges)
+	Mode string `yaml:"mode"`
+	// MaxDeviation drops consensus quotes further than this fraction from the median
+	MaxDeviation float64 `yaml:"max_deviation"`
+	// HedgeDelay is how long hedged mode waits on an exchange before starting the next
+	HedgeDelay time.Duration `yaml:"hedge_delay"`
+}
+
+// StreamingConfig enables pricing coins from exchange ticker WebSockets
+type StreamingConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// MaxAge is how old a streamed ticker may be and still price a coin
+	MaxAge time.Duration `yaml:"max_age"`
+}
+
+// SymbolDiscoveryConfig controls checking configured symbols against exchange listings at startup
+type SymbolDiscoveryConfig struct {
+	Enabled bool   `yaml:"enabled"` // Warn about configured symbols the exchanges don't list
+	Fill    bool   `yaml:"fill"`    // Use discovered symbols where none (or a missing one) is configured
+	Quote   string `yaml:"quote"`   // Quote asset of discovered pairs
+}
+
+// CoinConfig describes a tracked coin and its exchange trading symbols
+type CoinConfig struct {
+	ID      string                 `yaml:"id"`
+	Name    string                 `yaml:"name"`
+	Symbols models.ExchangeSymbols `yaml:"symbols"`
+	// Ticker is the coin's base asset on exchanges (e.g. "SCR"), used for symbol discovery
+	Ticker string `yaml:"ticker,omitempty"`
+	// Exchanges overrides the global exchange order for this coin
+	Exchanges []string `yaml:"exchanges,omitempty"`
+}
+
+// Default returns the built-in configuration used when no config file exists
+func Default() *Config {
+	coins := []CoinConfig{
+		{Name: "Starknet", ID: "starknet", Ticker: "STRK"},
+		{Name: "ZkSync", ID: "zksync", Ticker: "ZK"},
+		{Name: "Taiko", ID: "taiko", Ticker: "TAIKO"},
+		{Name: "Scroll", ID: "scroll", Ticker: "SCR"},
+		{Name: "Movement", ID: "movement", Ticker: "MOVE"},
+		{Name: "Polyhedra", ID: "polyhedra-network", Ticker: "ZKJ"},
+		{Name: "Linea", ID: "linea", Ticker: "LINEA"},
+	}
+
+	cfg := &Config{
+		Telegram: TelegramConfig{
+			Mode: TelegramModePolling,
+			Webhook: WebhookConfig{
+				ListenAddr: ":8443",
+				Path:       "/telegram",
+			},
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: logging.FormatText,
+		},
+		HTTPTimeout:            10 * time.Second,
+		CoinDataUpdateInterval: 5 * time.Minute,
+		CoinDataUpdateTimeout:  time.Minute,
+		SupplyTTL:              models.SupplyTTL,
+		VolumeTTL:              models.VolumeTTL,
+		LastGoodGrace:          time.Hour,
+		SupplyCacheFile:        "supply_cache.json",
+		Exchanges:              []string{"binance", "okx", "bybit", "bitget"},
+		SymbolDiscovery: SymbolDiscoveryConfig{
+			Enabled: true,
+			Quote:   "USDT",
+		},
+		ReloadCheckInterval: 30 * time.Second,
+		ShutdownTimeout:     15 * time.Second,
+		Monitoring: MonitoringConfig{
+			MaxDataAge: 15 * time.Minute,
+		},
+		CoinGeckoBreaker: BreakerConfig{
+			FailureThreshold: 3,
+			Cooldown:         10 * time.Minute,
+		},
+		Retry: RetryConfig{
+			MaxAttempts: 3,
+			BaseDelay:   250 * time.Millisecond,
+			MaxDelay:    2 * time.Second,
+		},
+		Fallback: FallbackConfig{
+			Mode:         "sequential",
+			MaxDeviation: 0.05,
+			HedgeDelay:   500 * time.Millisecond,
+		},
+		Streaming: StreamingConfig{
+			MaxAge: 30 * time.Second,
+		},
+		GasNetworks: []models.GasNetwork{
+			{ID: "ethereum", Name: "Ethereum", Icon: "⬙", RPC: "https://rpc.mevblocker.io"},
+			{ID: "zksync", Name: "ZkSync", Icon: "⇆", RPC: "https://mainnet.era.zksync.io"},
+			{ID: "taiko", Name: "Taiko", Icon: "▲", RPC: "https://rpc.mainnet.taiko.xyz"},
+			{ID: "scroll", Name: "Scroll", Icon: "📜", RPC: "https://rpc.scroll.io"},
+		},
+	}
+	for _, coin := range coins {
+		coin.Symbols = models.Symbols[coin.ID]
+		cfg.Coins = append(cfg.Coins, coin)
+	}
+	return cfg
+}
+
+// Load reads the config file at path, applies environment overrides and validates the result.
+// A missing file is not an error: the built-in defaults are used instead.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		f, err := os.Open(path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			slog.Info("Config file not found, using defaults", "path", path)
+		case err != nil:
+			return nil, fmt.Errorf("open config: %w", err)
+		default:
+			defer f.Close()
+			// JSON is a subset of YAML, so the same decoder handles both formats
+			dec := yaml.NewDecoder(f)
+			dec.KnownFields(true)
+			if err := dec.Decode(cfg); err != nil {
+				return nil, fmt.Errorf("parse config %s: %w", path, err)
+			}
+			slog.Info("Loaded config", "path", path)
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.mergeSymbolsFile(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return cfg, nil
+}
+
+// applyEnv overrides config values with the optional variables documented in .env.example
+func (c *Config) applyEnv() error {
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		c.Log.Level = v
+	}
+	if v := os.Getenv("LOG_FORMAT"); v != "" {
+		c.Log.Format = v
+	}
+
+	if v := os.Getenv("TELEGRAM_WEBHOOK_SECRET"); v != "" {
+		c.Telegram.Webhook.SecretToken = v
+	}
+
+	if v := os.Getenv("SYMBOLS_JSON"); v != "" {
+		c.SymbolsFile = v
+	}
+
+	overrides := []struct {
+		name string
+		unit time.Duration
+		dst  *time.Duration
+	}{
+		{"HTTP_TIMEOUT_SECONDS", time.Second, &c.HTTPTimeout},
+		{"SUPPLY_TTL_HOURS", time.Hour, &c.SupplyTTL},
+		{"VOLUME_TTL_MINUTES", time.Minute, &c.VolumeTTL},
+	}
+	for _, o := range overrides {
+		v := os.Getenv(o.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("parse %s: %w", o.name, err)
+		}
+		*o.dst = time.Duration(n) * o.unit
+	}
+	return nil
+}
+
+// mergeSymbolsFile applies symbol overrides from SymbolsFile to the configured coins
+func (c *Config) mergeSymbolsFile() error {
+	custom, err := models.LoadSymbolsFromJSON(c.SymbolsFile)
+	if err != nil {
+		return fmt.Errorf("load symbols file: %w", err)
+	}
+
+	for coinID, symbols := range custom {
+		found := false
+		for i := range c.Coins {
+			if c.Coins[i].ID == coinID {
+				c.Coins[i].Symbols = symbols
+				found = true
+			}
+		}
+		if !found {
+			slog.Warn("Symbols file maps unknown coin, ignoring", "coin", coinID)
+		}
+	}
+	return nil
+}
+
+// Validate reports every problem found in the config at once
+func (c *Config) Validate() error {
+	var errs []error
+
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"http_timeout", c.HTTPTimeout},
+		{"coin_data_update_interval", c.CoinDataUpdateInterval},
+		{"coin_data_update_timeout", c.CoinDataUpdateTimeout},
+		{"supply_ttl", c.SupplyTTL},
+		{"volume_ttl", c.VolumeTTL},
+		{"shutdown_timeout", c.ShutdownTimeout},
+		{"monitoring.max_data_age", c.Monitoring.MaxDataAge},
+		{"coingecko_breaker.cooldown", c.CoinGeckoBreaker.Cooldown},
+		{"retry.base_delay", c.Retry.BaseDelay},
+		{"retry.max_delay", c.Retry.MaxDelay},
+	}
+	for _, d := range durations {
+		if d.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive, got %s", d.name, d.value))
+		}
+	}
+
+	switch c.Telegram.Mode {
+	case TelegramModePolling:
+	case TelegramModeWebhook:
+		errs = append(errs, c.Telegram.Webhook.validate()...)
+	default:
+		errs = append(errs, fmt.Errorf("telegram.mode must be %q or %q, got %q", TelegramModePolling, TelegramModeWebhook, c.Telegram.Mode))
+	}
+
+	if _, err := logging.NewHandler(io.Discard, c.Log.Level, c.Log.Format); err != nil {
+		errs = append(errs, fmt.Errorf("log: %w", err))
+	}
+
+	if c.CoinGeckoBreaker.FailureThreshold < 1 {
+		errs = append(errs, fmt.Errorf("coingecko_breaker.failure_threshold must be at least 1, got %d", c.CoinGeckoBreaker.FailureThreshold))
+	}
+
+	if c.Retry.MaxAttempts < 1 {
+		errs = append(errs, fmt.Errorf("retry.max_attempts must be at least 1, got %d", c.Retry.MaxAttempts))
+	}
+
+	switch c.Fallback.Mode {
+	case "sequential", "hedged", "consensus":
+	default:
+		errs = append(errs, fmt.Errorf("fallback.mode must be \"sequential\", \"hedged\" or \"consensus\", got %q", c.Fallback.Mode))
+	}
+	if c.Fallback.MaxDeviation <= 0 {
+		errs = append(errs, fmt.Errorf("fallback.max_deviation must be positive, got %g", c.Fallback.MaxDeviation))
+	}
+	if c.Fallback.HedgeDelay <= 0 {
+		errs = append(errs, fmt.Errorf("fallback.hedge_delay must be positive, got %s", c.Fallback.HedgeDelay))
+	}
+
+	if c.Streaming.Enabled && c.Streaming.MaxAge <= 0 {
+		errs = append(errs, fmt.Errorf("streaming.max_age must be positive, got %s", c.Streaming.MaxAge))
+	}
+
+	if c.LastGoodGrace < 0 {
+		errs = append(errs, fmt.Errorf("last_good_grace must not be negative, got %s", c.LastGoodGrace))
+	}
+
+	if c.ReloadCheckInterval < 0 {
+		errs = append(errs, fmt.Errorf("reload_check_interval must not be negative, got %s", c.ReloadCheckInterval))
+	}
+
+	if len(c.Exchanges) == 0 {
+		errs = append(errs, errors.New("exchanges: at least one exchange is required"))
+	}
+	errs = append(errs, validateExchanges("exchanges", c.Exchanges)...)
+
+	if c.SymbolDiscovery.Enabled && c.SymbolDiscovery.Quote == "" {
+		errs = append(errs, errors.New("symbol_discovery.quote is required when discovery is enabled"))
+	}
+
+	if len(c.Coins) == 0 {
+		errs = append(errs, errors.New("coins: at least one coin is required"))
+	}
+	seenCoins := make(map[string]bool)
+	for i, coin := range c.Coins {
+		if coin.ID == "" {
+			errs = append(errs, fmt.Errorf("coins[%d]: id is required", i))
+			continue
+		}
+		if seenCoins[coin.ID] {
+			errs = append(errs, fmt.Errorf("coins[%d]: duplicate id %q", i, coin.ID))
+		}
+		seenCoins[coin.ID] = true
+		if coin.Name == "" {
+			errs = append(errs, fmt.Errorf("coins[%d] (%s): name is required", i, coin.ID))
+		}
+		for exchange := range coin.Symbols {
+			if !exchanges.Registered(exchange) {
+				errs = append(errs, fmt.Errorf("coins[%d] (%s): unknown exchange %q in symbols, want one of %s", i, coin.ID, exchange, strings.Join(exchanges.Names(), ", ")))
+			}
+		}
+		errs = append(errs, validateExchanges(fmt.Sprintf("coins[%d] (%s): exchanges", i, coin.ID), coin.Exchanges)...)
+	}
+
+	seenNetworks := make(map[string]bool)
+	for i, network := range c.GasNetworks {
+		if network.ID == "" {
+			errs = append(errs, fmt.Errorf("gas_networks[%d]: id is required", i))
+			continue
+		}
+		if seenNetworks[network.ID] {
+			errs = append(errs, fmt.Errorf("gas_networks[%d]: duplicate id %q", i, network.ID))
+		}
+		seenNetworks[network.ID] = true
+		if network.Name == "" {
+			errs = append(errs, fmt.Errorf("gas_networks[%d] (%s): name is required", i, network.ID))
+		}
+		if u, err := url.Parse(network.RPC); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("gas_networks[%d] (%s): invalid rpc url %q", i, network.ID, network.RPC))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateExchanges checks that an exchange order only names registered
+// providers, each at most once
+func validateExchanges(field string, names []string) []error {
+	var errs []error
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if !exchanges.Registered(name) {
+			errs = append(errs, fmt.Errorf("%s: unknown exchange %q, want one of %s", field, name, strings.Join(exchanges.Names(), ", ")))
+		}
+		if seen[name] {
+			errs = append(errs, fmt.Errorf("%s: duplicate exchange %q", field, name))
+		}
+		seen[name] = true
+	}
+	return errs
+}
+
+func (w *WebhookConfig) validate() []error {
+	var errs []error
+	if u, err := url.Parse(w.URL); err != nil || u.Scheme != "https" || u.Host == "" {
+		errs = append(errs, fmt.Errorf("telegram.webhook.url must be an https url, got %q", w.URL))
+	}
+	if w.ListenAddr == "" {
+		errs = append(errs, errors.New("telegram.webhook.listen_addr is required"))
+	}
+	if !strings.HasPrefix(w.Path, "/") {
+		errs = append(errs, fmt.Errorf("telegram.webhook.path must start with /, got %q", w.Path))
+	}
+	// Telegram accepts 1-256 characters from A-Z, a-z, 0-9, _ and -
+	if len(w.SecretToken) == 0 || len(w.SecretToken) > 256 || strings.Trim(w.SecretToken, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789_-") != "" {
+		errs = append(errs, errors.New("telegram.webhook.secret_token must be 1-256 characters of A-Z, a-z, 0-9, _ or -"))
+	}
+	return errs
+}
+
+// CoinList returns the tracked coins in config order
+func (c *Config) CoinList() []models.Coin {
+	coins := make([]models.Coin, 0, len(c.Coins))
+	for _, coin := range c.Coins {
+		coins = append(coins, models.Coin{Name: coin.Name, ID: coin.ID})
+	}
+	return coins
+}
+
+// RetryPolicy returns the retry policy shared by all upstream clients
+func (c *Config) RetryPolicy() exchanges.RetryPolicy {
+	return exchanges.RetryPolicy{
+		MaxAttempts: c.Retry.MaxAttempts,
+		BaseDelay:   c.Retry.BaseDelay,
+		MaxDelay:    c.Retry.MaxDelay,
+	}
+}
+
+// ExchangeOrder returns the exchanges to try for each coin, in order, keyed by coin ID
+func (c *Config) ExchangeOrder() map[string][]string {
+	order := make(map[string][]string, len(c.Coins))
+	for _, coin := range c.Coins {
+		if len(coin.Exchanges) > 0 {
+			order[coin.ID] = coin.Exchanges
+		} else {
+			order[coin.ID] = c.Exchanges
+		}
+	}
+	return order
+}
+
+// Symbols returns the exchange symbol mappings keyed by coin ID
+func (c *Config) Symbols() map[string]models.ExchangeSymbols {
+	symbols := make(map[string]models.ExchangeSymbols, len(c.Coins))
+	for _, coin := range c.Coins {
+		symbols[coin.ID] = coin.Symbols
+	}
+	return symbols
+}
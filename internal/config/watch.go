@@ -0,0 +1,70 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// Watch reloads the config at path when a signal arrives on sighup and, when
+// interval is positive, whenever the modification time of the file or of the
+// symbols file it points to (initially symbolsFile) changes. The caller
+// registers sighup with signal.Notify early, so a SIGHUP during startup is queued
+// instead of killing the process. onReload is only called with configs that
+// loaded and validated successfully; otherwise the error is logged and the
+// previous config stays in effect. Watch returns when ctx is canceled.
+func Watch(ctx context.Context, path, symbolsFile string, interval time.Duration, sighup <-chan os.Signal, onReload func(context.Context, *Config)) {
+	var poll <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+
+	lastMod, lastSymbolsMod := modTime(path), modTime(symbolsFile)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sighup:
+			slog.Info("Received SIGHUP, reloading config", "path", path)
+		case <-poll:
+			switch {
+			case !modTime(path).Equal(lastMod):
+				slog.Info("Config file changed, reloading", "path", path)
+			case !modTime(symbolsFile).Equal(lastSymbolsMod):
+				slog.Info("Symbols file changed, reloading", "path", symbolsFile)
+			default:
+				continue
+			}
+		}
+		lastMod, lastSymbolsMod = modTime(path), modTime(symbolsFile)
+
+		cfg, err := Load(path)
+		if err != nil {
+			slog.Error("Config reload failed, keeping previous config", "error", err)
+			continue
+		}
+
+		// The reloaded config may point at a different symbols file
+		if cfg.SymbolsFile != symbolsFile {
+			symbolsFile = cfg.SymbolsFile
+			lastSymbolsMod = modTime(symbolsFile)
+		}
+		onReload(ctx, cfg)
+	}
+}
+
+// modTime returns the file's modification time, or the zero time if path is empty
+// or the file can't be read
+func modTime(path string) time.Time {
+	if path == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
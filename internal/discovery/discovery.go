@@ -0,0 +1,148 @@
+package discovery
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+
+	"scroll-rank-bot/internal/config"
+	"scroll-rank-bot/internal/exchanges"
+	"scroll-rank-bot/internal/models"
+)
+
+// Status of a coin's symbol on one exchange
+const (
+	StatusOK       = "ok"       // Configured symbol is listed
+	StatusMissing  = "missing"  // Configured symbol isn't listed
+	StatusProposed = "proposed" // Nothing configured, but a matching pair is listed
+	StatusUnlisted = "unlisted" // Nothing configured and nothing listed
+)
+
+// Finding is the result of checking one coin on one exchange
+type Finding struct {
+	Coin       string // Coin ID
+	Exchange   string
+	Configured string // Symbol from the config, empty if none
+	Listed     string // Listed pair matching the coin's ticker and quote, empty if none
+	Status     string
+}
+
+// Check lists every exchange's instruments and compares them with the configured
+// symbols of coins that have a ticker. Exchanges whose listing fails are logged
+// and left out of the findings.
+func Check(ctx context.Context, providers map[string]exchanges.Provider, coins []config.CoinConfig, quote string) []Finding {
+	listings := listInstruments(ctx, providers)
+
+	names := make([]string, 0, len(listings))
+	for name := range listings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var findings []Finding
+	for _, coin := range coins {
+		if coin.Ticker == "" {
+			slog.Debug("Coin has no ticker, skipping symbol discovery", "coin", coin.ID)
+			continue
+		}
+		for _, name := range names {
+			findings = append(findings, check(coin, name, listings[name], quote))
+		}
+	}
+	return findings
+}
+
+// check compares one coin's configured symbol with one exchange's listing
+func check(coin config.CoinConfig, exchange string, instruments []exchanges.Instrument, quote string) Finding {
+	f := Finding{Coin: coin.ID, Exchange: exchange, Configured: coin.Symbols[exchange]}
+
+	configuredListed := false
+	for _, inst := range instruments {
+		if f.Configured != "" && inst.Symbol == f.Configured {
+			configuredListed = true
+		}
+		if strings.EqualFold(inst.Base, coin.Ticker) && strings.EqualFold(inst.Quote, quote) {
+			f.Listed = inst.Symbol
+		}
+	}
+
+	switch {
+	case configuredListed:
+		f.Status = StatusOK
+	case f.Configured != "":
+		f.Status = StatusMissing
+	case f.Listed != "":
+		f.Status = StatusProposed
+	default:
+		f.Status = StatusUnlisted
+	}
+	return f
+}
+
+// listInstruments fetches the instrument lists of all providers that support it concurrently
+func listInstruments(ctx context.Context, providers map[string]exchanges.Provider) map[string][]exchanges.Instrument {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		listings = make(map[string][]exchanges.Instrument)
+	)
+
+	for name, provider := range providers {
+		lister, ok := provider.(exchanges.InstrumentLister)
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(name string, lister exchanges.InstrumentLister) {
+			defer wg.Done()
+			instruments, err := lister.ListInstruments(ctx)
+			if err != nil {
+				slog.Warn("Failed to list exchange instruments", "provider", name, "error", err)
+				return
+			}
+			mu.Lock()
+			listings[name] = instruments
+			mu.Unlock()
+		}(name, lister)
+	}
+	wg.Wait()
+	return listings
+}
+
+// Log warns about configured symbols the exchange doesn't list and reports proposed ones
+func Log(findings []Finding) {
+	for _, f := range findings {
+		switch f.Status {
+		case StatusMissing:
+			slog.Warn("Configured symbol not listed on exchange", "coin", f.Coin, "provider", f.Exchange, "symbol", f.Configured, "listed", f.Listed)
+		case StatusProposed:
+			slog.Info("Exchange lists coin without a configured symbol", "coin", f.Coin, "provider", f.Exchange, "symbol", f.Listed)
+		}
+	}
+}
+
+// Fill sets the proposed symbols on coins that have none configured for the
+// exchange, and replaces missing ones where the exchange lists a matching pair
+func Fill(cfg *config.Config, findings []Finding) {
+	for _, f := range findings {
+		if f.Listed == "" || (f.Status != StatusProposed && f.Status != StatusMissing) {
+			continue
+		}
+		for i := range cfg.Coins {
+			coin := &cfg.Coins[i]
+			if coin.ID != f.Coin {
+				continue
+			}
+			// Copy before writing, the map may be shared with models.Symbols
+			symbols := make(models.ExchangeSymbols, len(coin.Symbols)+1)
+			for exchange, symbol := range coin.Symbols {
+				symbols[exchange] = symbol
+			}
+			symbols[f.Exchange] = f.Listed
+			coin.Symbols = symbols
+			slog.Info("Filled discovered symbol", "coin", f.Coin, "provider", f.Exchange, "symbol", f.Listed, "replaced", f.Configured)
+		}
+	}
+}
@@ -1,23 +1,35 @@
 package exchanges
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"log/slog"
 	"net/http"
+	neturl "net/url"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type BinanceProvider struct {
 	client  *http.Client
 	baseURL string
+	wsURL   string
+	limiter *Limiter
+}
+
+func init() {
+	Register("binance", "Binance", func(timeout time.Duration) Provider { return NewBinanceProvider(timeout) })
 }
 
 func NewBinanceProvider(timeout time.Duration) *BinanceProvider {
 	return &BinanceProvider{
 		client:  SharedHTTPClient(timeout),
+		limiter: NewLimiter("binance"),
 		baseURL: "https://api.binance.com",
+		wsURL:   "wss://stream.binance.com:9443",
 	}
 }
 
@@ -25,53 +37,186 @@ func (b *BinanceProvider) Name() string {
 	return "binance"
 }
 
+func (b *BinanceProvider) SuspendedUntil() time.Time {
+	return b.limiter.SuspendedUntil()
+}
+
+// binanceWeightLimit is Binance's default request weight budget per minute per IP
+const binanceWeightLimit = 6000
+
+// observeBinanceWeight suspends Binance until the next minute once the used
+// request weight (X-MBX-USED-WEIGHT-1M) gets close to the limit
+func observeBinanceWeight(l *Limiter, h http.Header) {
+	used, err := strconv.Atoi(h.Get("X-MBX-USED-WEIGHT-1M"))
+	if err != nil {
+		return
+	}
+	if used >= binanceWeightLimit*9/10 {
+		l.Suspend(time.Now().Truncate(time.Minute).Add(time.Minute), fmt.Sprintf("used weight %d/%d", used, binanceWeightLimit))
+	}
+}
+
+// binanceErrorKinds classifies Binance error codes
+var binanceErrorKinds = map[string]error{
+	"-1121": ErrSymbolNotSupported, // Invalid symbol
+	"-1003": ErrRateLimited,        // Too many requests
+	"-1015": ErrRateLimited,        // Too many new orders
+	"-1000": ErrServer,             // Unknown error
+	"-1001": ErrServer,             // Internal error, disconnected
+	"-1007": ErrServer,             // Timeout waiting for backend
+	"-1016": ErrMaintenance,        // Service shutting down
+}
+
+// binanceError classifies a non-200 Binance response by its {"code","msg"} payload
+func binanceError(status int, body []byte) error {
+	var payload struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || payload.Code == 0 {
+		return &HTTPError{StatusCode: status, Body: string(body)}
+	}
+	return newAPIError(binanceErrorKinds, status, strconv.Itoa(payload.Code), payload.Msg)
+}
+
 type binanceTicker24hr struct {
+	Symbol             string `json:"symbol"`
 	LastPrice          string `json:"lastPrice"`
 	PriceChangePercent string `json:"priceChangePercent"`
+	QuoteVolume        string `json:"quoteVolume"`
 }
 
-func (b *BinanceProvider) GetPriceAndChange(symbol string) (price float64, changePct24h float64, err error) {
+func (b *BinanceProvider) GetTicker(ctx context.Context, symbol string) (*Ticker, error) {
 	if symbol == "" {
-		return 0, 0, NewProviderError(b.Name(), symbol, ErrSymbolNotSupported)
+		return nil, NewProviderError(b.Name(), symbol, ErrSymbolNotSupported)
 	}
 
+	var ticker binanceTicker24hr
 	url := fmt.Sprintf("%s/api/v3/ticker/24hr?symbol=%s", b.baseURL, symbol)
-	req, err := http.NewRequest("GET", url, nil)
+	if err := getJSON(ctx, b.client, b.limiter, observeBinanceWeight, b.Name(), symbol, binanceError, url, &ticker); err != nil {
+		return nil, err
+	}
+
+	t, err := ticker.ticker()
 	if err != nil {
-		return 0, 0, NewProviderError(b.Name(), symbol, err)
+		return nil, NewProviderError(b.Name(), symbol, err)
 	}
+	return t, nil
+}
 
-	req.Header.Set("User-Agent", DefaultUserAgent())
+// GetTickers fetches the 24h tickers of all symbols in one request. Binance rejects
+// the whole request (-1121) if any symbol is unlisted, so then all tickers are
+// fetched instead (weight 80 rather than 2 per symbol) and the unlisted symbols
+// are left out.
+func (b *BinanceProvider) GetTickers(ctx context.Context, symbols []string) (map[string]*Ticker, error) {
+	list, err := json.Marshal(symbols)
+	if err != nil {
+		return nil, NewProviderError(b.Name(), "", err)
+	}
 
-	resp, err := b.client.Do(req)
+	var tickers []binanceTicker24hr
+	url := fmt.Sprintf("%s/api/v3/ticker/24hr?symbols=%s", b.baseURL, neturl.QueryEscape(string(list)))
+	err = getJSON(ctx, b.client, b.limiter, observeBinanceWeight, b.Name(), "", binanceError, url, &tickers)
+	if errors.Is(err, ErrSymbolNotSupported) {
+		slog.Warn("Binance rejected batch over an unlisted symbol, fetching all tickers", "provider", b.Name(), "symbols", len(symbols), "error", err)
+		tickers = nil
+		err = getJSON(ctx, b.client, b.limiter, observeBinanceWeight, b.Name(), "", binanceError, b.baseURL+"/api/v3/ticker/24hr", &tickers)
+	}
 	if err != nil {
-		return 0, 0, NewProviderError(b.Name(), symbol, err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusTooManyRequests {
-		return 0, 0, NewProviderError(b.Name(), symbol, ErrRateLimited)
+	wanted := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		wanted[symbol] = true
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return 0, 0, NewProviderError(b.Name(), symbol, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body)))
+	result := make(map[string]*Ticker, len(symbols))
+	for _, ticker := range tickers {
+		if !wanted[ticker.Symbol] {
+			continue
+		}
+		if t, err := ticker.ticker(); err == nil {
+			result[ticker.Symbol] = t
+		}
 	}
+	return result, nil
+}
 
-	var ticker binanceTicker24hr
-	if err := json.NewDecoder(resp.Body).Decode(&ticker); err != nil {
-		return 0, 0, NewProviderError(b.Name(), symbol, err)
+func (t binanceTicker24hr) ticker() (*Ticker, error) {
+	price, err := strconv.ParseFloat(t.LastPrice, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parse lastPrice: %w", err)
 	}
 
-	price, err = strconv.ParseFloat(ticker.LastPrice, 64)
+	changePct24h, err := strconv.ParseFloat(t.PriceChangePercent, 64)
 	if err != nil {
-		return 0, 0, NewProviderError(b.Name(), symbol, fmt.Errorf("parse lastPrice: %w", err))
+		return nil, fmt.Errorf("parse priceChangePercent: %w", err)
 	}
 
-	changePct24h, err = strconv.ParseFloat(ticker.PriceChangePercent, 64)
+	quoteVolume, err := strconv.ParseFloat(t.QuoteVolume, 64)
 	if err != nil {
-		return 0, 0, NewProviderError(b.Name(), symbol, fmt.Errorf("parse priceChangePercent: %w", err))
+		return nil, fmt.Errorf("parse quoteVolume: %w", err)
+	}
+
+	return &Ticker{Price: price, ChangePct24h: changePct24h, QuoteVolume24h: quoteVolume}, nil
+}
+
+type binanceExchangeInfo struct {
+	Symbols []struct {
+		Symbol     string `json:"symbol"`
+		Status     string `json:"status"`
+		BaseAsset  string `json:"baseAsset"`
+		QuoteAsset string `json:"quoteAsset"`
+	} `json:"symbols"`
+}
+
+// ListInstruments returns the spot pairs currently trading on Binance
+func (b *BinanceProvider) ListInstruments(ctx context.Context) ([]Instrument, error) {
+	var info binanceExchangeInfo
+	if err := getJSON(ctx, b.client, b.limiter, observeBinanceWeight, b.Name(), "", binanceError, b.baseURL+"/api/v3/exchangeInfo?permissions=SPOT", &info); err != nil {
+		return nil, err
+	}
+
+	instruments := make([]Instrument, 0, len(info.Symbols))
+	for _, s := range info.Symbols {
+		if s.Status == "TRADING" {
+			instruments = append(instruments, Instrument{Symbol: s.Symbol, Base: s.BaseAsset, Quote: s.QuoteAsset})
+		}
+	}
+	return instruments, nil
+}
+
+// binanceStreamTicker is a 24hrTicker event from the Binance ticker stream
+type binanceStreamTicker struct {
+	Symbol             string `json:"s"`
+	LastPrice          string `json:"c"`
+	PriceChangePercent string `json:"P"`
+	QuoteVolume        string `json:"q"`
+}
+
+// TickerStream subscribes to the <symbol>@ticker streams through a combined stream URL;
+// Binance pings us and the connection answers with pongs, so no heartbeat is sent
+func (b *BinanceProvider) TickerStream(symbols []string) Stream {
+	streams := make([]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		streams = append(streams, strings.ToLower(symbol)+"@ticker")
 	}
 
-	return price, changePct24h, nil
+	return Stream{
+		URL: b.wsURL + "/stream?streams=" + strings.Join(streams, "/"),
+		Parse: func(msg []byte) map[string]*Ticker {
+			var event struct {
+				Data binanceStreamTicker `json:"data"`
+			}
+			if err := json.Unmarshal(msg, &event); err != nil || event.Data.Symbol == "" {
+				return nil
+			}
+			t, err := binanceTicker24hr(event.Data).ticker()
+			if err != nil {
+				return nil
+			}
+			return map[string]*Ticker{event.Data.Symbol: t}
+		},
+	}
 }
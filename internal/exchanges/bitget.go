@@ -1,9 +1,9 @@
 package exchanges
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"strconv"
 	"time"
@@ -12,12 +12,20 @@ import (
 type BitgetProvider struct {
 	client  *http.Client
 	baseURL string
+	wsURL   string
+	limiter *Limiter
+}
+
+func init() {
+	Register("bitget", "Bitget", func(timeout time.Duration) Provider { return NewBitgetProvider(timeout) })
 }
 
 func NewBitgetProvider(timeout time.Duration) *BitgetProvider {
 	return &BitgetProvider{
 		client:  SharedHTTPClient(timeout),
+		limiter: NewLimiter("bitget"),
 		baseURL: "https://api.bitget.com",
+		wsURL:   "wss://ws.bitget.com/v2/ws/public",
 	}
 }
 
@@ -25,69 +33,189 @@ func (b *BitgetProvider) Name() string {
 	return "bitget"
 }
 
+func (b *BitgetProvider) SuspendedUntil() time.Time {
+	return b.limiter.SuspendedUntil()
+}
+
+// bitgetErrorKinds classifies Bitget v2 error codes
+var bitgetErrorKinds = map[string]error{
+	"40034": ErrSymbolNotSupported, // Parameter does not exist, returned for unknown symbols
+	"40808": ErrBadRequest,         // Parameter verification exception
+	"429":   ErrRateLimited,        // Too many requests
+	"40010": ErrServer,             // Request timed out
+	"40725": ErrServer,             // Service returned an error
+	"45001": ErrServer,             // Unknown error
+}
+
+// bitgetError classifies a non-200 Bitget response by its {"code","msg"} payload
+func bitgetError(status int, body []byte) error {
+	var payload struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || payload.Code == "" || payload.Code == "00000" {
+		return &HTTPError{StatusCode: status, Body: string(body)}
+	}
+	return newAPIError(bitgetErrorKinds, status, payload.Code, payload.Msg)
+}
+
+type bitgetTicker struct {
+	Symbol     string `json:"symbol"`
+	LastPr     string `json:"lastPr"`     // Last price
+	Change24h  string `json:"change24h"`  // This is a decimal (e.g., "0.0123" means 1.23%)
+	UsdtVolume string `json:"usdtVolume"` // 24h volume in USDT
+}
+
 type bitgetTickerResponse struct {
-	Code string `json:"code"`
-	Msg  string `json:"msg"`
-	Data struct {
-		Close string `json:"close"` // Last price
-		Open  string `json:"open"`  // 24h open price
-	} `json:"data"`
+	Code string         `json:"code"`
+	Msg  string         `json:"msg"`
+	Data []bitgetTicker `json:"data"`
 }
 
-func (b *BitgetProvider) GetPriceAndChange(symbol string) (price float64, changePct24h float64, err error) {
+func (b *BitgetProvider) GetTicker(ctx context.Context, symbol string) (*Ticker, error) {
 	if symbol == "" {
-		return 0, 0, NewProviderError(b.Name(), symbol, ErrSymbolNotSupported)
+		return nil, NewProviderError(b.Name(), symbol, ErrSymbolNotSupported)
 	}
 
-	url := fmt.Sprintf("%s/api/spot/v1/market/ticker?symbol=%s", b.baseURL, symbol)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return 0, 0, NewProviderError(b.Name(), symbol, err)
+	var tickerResp bitgetTickerResponse
+	url := fmt.Sprintf("%s/api/v2/spot/market/tickers?symbol=%s", b.baseURL, symbol)
+	if err := getJSON(ctx, b.client, b.limiter, nil, b.Name(), symbol, bitgetError, url, &tickerResp); err != nil {
+		return nil, err
 	}
 
-	req.Header.Set("User-Agent", DefaultUserAgent())
-
-	resp, err := b.client.Do(req)
-	if err != nil {
-		return 0, 0, NewProviderError(b.Name(), symbol, err)
+	// Bitget returns code "00000" for success
+	if tickerResp.Code != "00000" {
+		err := newAPIError(bitgetErrorKinds, http.StatusOK, tickerResp.Code, tickerResp.Msg)
+		b.limiter.ObserveError(err)
+		return nil, NewProviderError(b.Name(), symbol, err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusTooManyRequests {
-		return 0, 0, NewProviderError(b.Name(), symbol, ErrRateLimited)
+	if len(tickerResp.Data) == 0 {
+		return nil, NewProviderError(b.Name(), symbol, fmt.Errorf("no data returned"))
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return 0, 0, NewProviderError(b.Name(), symbol, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body)))
+	t, err := tickerResp.Data[0].ticker()
+	if err != nil {
+		return nil, NewProviderError(b.Name(), symbol, err)
 	}
+	return t, nil
+}
 
+// GetTickers fetches all spot tickers in one request and keeps those of symbols
+func (b *BitgetProvider) GetTickers(ctx context.Context, symbols []string) (map[string]*Ticker, error) {
 	var tickerResp bitgetTickerResponse
-	if err := json.NewDecoder(resp.Body).Decode(&tickerResp); err != nil {
-		return 0, 0, NewProviderError(b.Name(), symbol, err)
+	if err := getJSON(ctx, b.client, b.limiter, nil, b.Name(), "", bitgetError, b.baseURL+"/api/v2/spot/market/tickers", &tickerResp); err != nil {
+		return nil, err
 	}
 
-	// Bitget returns code "00000" for success
 	if tickerResp.Code != "00000" {
-		return 0, 0, NewProviderError(b.Name(), symbol, fmt.Errorf("Bitget API error: %s (code %s)", tickerResp.Msg, tickerResp.Code))
+		err := newAPIError(bitgetErrorKinds, http.StatusOK, tickerResp.Code, tickerResp.Msg)
+		b.limiter.ObserveError(err)
+		return nil, NewProviderError(b.Name(), "", err)
 	}
 
-	close, err := strconv.ParseFloat(tickerResp.Data.Close, 64)
+	wanted := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		wanted[symbol] = true
+	}
+
+	result := make(map[string]*Ticker, len(symbols))
+	for _, ticker := range tickerResp.Data {
+		if !wanted[ticker.Symbol] {
+			continue
+		}
+		if t, err := ticker.ticker(); err == nil {
+			result[ticker.Symbol] = t
+		}
+	}
+	return result, nil
+}
+
+func (t bitgetTicker) ticker() (*Ticker, error) {
+	price, err := strconv.ParseFloat(t.LastPr, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parse lastPr: %w", err)
+	}
+
+	// change24h is in decimal form (e.g., "0.0123" = 1.23%), multiply by 100 to get percentage
+	changeDecimal, err := strconv.ParseFloat(t.Change24h, 64)
 	if err != nil {
-		return 0, 0, NewProviderError(b.Name(), symbol, fmt.Errorf("parse close: %w", err))
+		return nil, fmt.Errorf("parse change24h: %w", err)
 	}
 
-	open, err := strconv.ParseFloat(tickerResp.Data.Open, 64)
+	usdtVolume, err := strconv.ParseFloat(t.UsdtVolume, 64)
 	if err != nil {
-		return 0, 0, NewProviderError(b.Name(), symbol, fmt.Errorf("parse open: %w", err))
+		return nil, fmt.Errorf("parse usdtVolume: %w", err)
+	}
+
+	return &Ticker{Price: price, ChangePct24h: changeDecimal * 100, QuoteVolume24h: usdtVolume}, nil
+}
+
+type bitgetSymbolsResponse struct {
+	Code string `json:"code"`
+	Msg  string `json:"msg"`
+	Data []struct {
+		Symbol    string `json:"symbol"`
+		BaseCoin  string `json:"baseCoin"`
+		QuoteCoin string `json:"quoteCoin"`
+		Status    string `json:"status"`
+	} `json:"data"`
+}
+
+// ListInstruments returns the spot pairs currently online on Bitget
+func (b *BitgetProvider) ListInstruments(ctx context.Context) ([]Instrument, error) {
+	var symbolsResp bitgetSymbolsResponse
+	if err := getJSON(ctx, b.client, b.limiter, nil, b.Name(), "", bitgetError, b.baseURL+"/api/v2/spot/public/symbols", &symbolsResp); err != nil {
+		return nil, err
+	}
+
+	if symbolsResp.Code != "00000" {
+		return nil, NewProviderError(b.Name(), "", newAPIError(bitgetErrorKinds, http.StatusOK, symbolsResp.Code, symbolsResp.Msg))
 	}
 
-	if open == 0 {
-		return 0, 0, NewProviderError(b.Name(), symbol, fmt.Errorf("open price is zero, cannot calculate change"))
+	instruments := make([]Instrument, 0, len(symbolsResp.Data))
+	for _, s := range symbolsResp.Data {
+		if s.Status == "online" {
+			instruments = append(instruments, Instrument{Symbol: s.Symbol, Base: s.BaseCoin, Quote: s.QuoteCoin})
+		}
 	}
+	return instruments, nil
+}
+
+// bitgetStreamTicker is a ticker push from the Bitget stream, which names the
+// symbol instId but otherwise matches the REST ticker
+type bitgetStreamTicker struct {
+	InstID string `json:"instId"`
+	bitgetTicker
+}
 
-	// Calculate 24h change percentage: (close/open - 1) * 100
-	changePct24h = (close/open - 1) * 100
+// TickerStream subscribes to the spot ticker channel of each symbol; Bitget drops
+// connections that haven't sent a "ping" for two minutes
+func (b *BitgetProvider) TickerStream(symbols []string) Stream {
+	args := make([]map[string]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		args = append(args, map[string]string{"instType": "SPOT", "channel": "ticker", "instId": symbol})
+	}
 
-	return close, changePct24h, nil
+	return Stream{
+		URL:          b.wsURL,
+		Subscribe:    []any{map[string]any{"op": "subscribe", "args": args}},
+		Ping:         []byte("ping"),
+		PingInterval: 30 * time.Second,
+		Parse: func(msg []byte) map[string]*Ticker {
+			var push struct {
+				Data []bitgetStreamTicker `json:"data"`
+			}
+			if err := json.Unmarshal(msg, &push); err != nil {
+				return nil
+			}
+			tickers := make(map[string]*Ticker, len(push.Data))
+			for _, ticker := range push.Data {
+				if t, err := ticker.ticker(); err == nil {
+					tickers[ticker.InstID] = t
+				}
+			}
+			return tickers
+		},
+	}
 }
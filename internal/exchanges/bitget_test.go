@@ -0,0 +1,88 @@
+package exchanges
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Recorded from GET /api/v2/spot/market/tickers?symbol=SCRUSDT
+const bitgetTickerPayload = `{
+  "code": "00000",
+  "msg": "success",
+  "requestTime": 1729072800123,
+  "data": [
+    {
+      "open": "0.7012",
+      "symbol": "SCRUSDT",
+      "high24h": "0.7420",
+      "low24h": "0.6891",
+      "lastPr": "0.7235",
+      "quoteVolume": "1834402.5512",
+      "baseVolume": "2573661.12",
+      "usdtVolume": "1834402.551234",
+      "ts": "1729072800000",
+      "bidPr": "0.7234",
+      "askPr": "0.7236",
+      "bidSz": "1520.45",
+      "askSz": "880.12",
+      "openUtc": "0.7101",
+      "changeUtc24h": "0.01887",
+      "change24h": "0.03180"
+    }
+  ]
+}`
+
+// Recorded from GET /api/v2/spot/market/tickers?symbol=NOPEUSDT (HTTP 400)
+const bitgetUnknownSymbolPayload = `{"code":"40034","msg":"Parameter NOPEUSDT does not exist","requestTime":1729072800456,"data":null}`
+
+func newTestBitget(t *testing.T, status int, payload string) *BitgetProvider {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/spot/market/tickers" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(payload))
+	}))
+	t.Cleanup(server.Close)
+
+	provider := NewBitgetProvider(time.Second)
+	provider.baseURL = server.URL
+	return provider
+}
+
+func TestBitgetGetTicker(t *testing.T) {
+	provider := newTestBitget(t, http.StatusOK, bitgetTickerPayload)
+
+	ticker, err := provider.GetTicker(context.Background(), "SCRUSDT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ticker.Price != 0.7235 {
+		t.Errorf("Price = %v, want 0.7235", ticker.Price)
+	}
+	if math.Abs(ticker.ChangePct24h-3.18) > 1e-9 {
+		t.Errorf("ChangePct24h = %v, want 3.18", ticker.ChangePct24h)
+	}
+	if ticker.QuoteVolume24h != 1834402.551234 {
+		t.Errorf("QuoteVolume24h = %v, want 1834402.551234", ticker.QuoteVolume24h)
+	}
+}
+
+func TestBitgetGetTickerUnknownSymbol(t *testing.T) {
+	provider := newTestBitget(t, http.StatusBadRequest, bitgetUnknownSymbolPayload)
+
+	_, err := provider.GetTicker(context.Background(), "NOPEUSDT")
+	if !errors.Is(err, ErrSymbolNotSupported) {
+		t.Fatalf("err = %v, want ErrSymbolNotSupported", err)
+	}
+	if IsRetryable(err) {
+		t.Error("unknown symbol should not be retried")
+	}
+}
@@ -1,23 +1,32 @@
 package exchanges
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type BybitProvider struct {
 	client  *http.Client
 	baseURL string
+	wsURL   string
+	limiter *Limiter
+}
+
+func init() {
+	Register("bybit", "Bybit", func(timeout time.Duration) Provider { return NewBybitProvider(timeout) })
 }
 
 func NewBybitProvider(timeout time.Duration) *BybitProvider {
 	return &BybitProvider{
 		client:  SharedHTTPClient(timeout),
+		limiter: NewLimiter("bybit"),
 		baseURL: "https://api.bybit.com",
+		wsURL:   "wss://stream.bybit.com/v5/public/spot",
 	}
 }
 
@@ -25,73 +34,205 @@ func (b *BybitProvider) Name() string {
 	return "bybit"
 }
 
+func (b *BybitProvider) SuspendedUntil() time.Time {
+	return b.limiter.SuspendedUntil()
+}
+
+// observeBybitLimit suspends Bybit until the window resets once no requests remain
+// (X-Bapi-Limit-Status) in the current window (X-Bapi-Limit-Reset-Timestamp, ms)
+func observeBybitLimit(l *Limiter, h http.Header) {
+	remaining, err := strconv.Atoi(h.Get("X-Bapi-Limit-Status"))
+	if err != nil || remaining > 0 {
+		return
+	}
+	resetMs, err := strconv.ParseInt(h.Get("X-Bapi-Limit-Reset-Timestamp"), 10, 64)
+	if err != nil {
+		return
+	}
+	l.Suspend(time.UnixMilli(resetMs), "request limit exhausted")
+}
+
+// bybitErrorKinds classifies Bybit retCodes
+var bybitErrorKinds = map[string]error{
+	"10001": ErrSymbolNotSupported, // Parameter error, returned for unknown symbols
+	"10006": ErrRateLimited,        // Too many visits
+	"10018": ErrRateLimited,        // IP rate limit exceeded
+	"10000": ErrServer,             // Server timeout
+	"10016": ErrServer,             // Server error
+}
+
+// bybitError classifies a non-200 Bybit response by its {"retCode","retMsg"} payload
+func bybitError(status int, body []byte) error {
+	var payload struct {
+		RetCode int    `json:"retCode"`
+		RetMsg  string `json:"retMsg"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || payload.RetCode == 0 {
+		return &HTTPError{StatusCode: status, Body: string(body)}
+	}
+	return newAPIError(bybitErrorKinds, status, strconv.Itoa(payload.RetCode), payload.RetMsg)
+}
+
+type bybitTicker struct {
+	Symbol       string `json:"symbol"`
+	LastPrice    string `json:"lastPrice"`
+	Price24hPcnt string `json:"price24hPcnt"` // This is a decimal (e.g., "0.0123" means 1.23%)
+	Turnover24h  string `json:"turnover24h"`  // 24h volume in the quote currency
+}
+
 type bybitTickerResponse struct {
 	RetCode int    `json:"retCode"`
 	RetMsg  string `json:"retMsg"`
 	Result  struct {
-		List []struct {
-			LastPrice    string `json:"lastPrice"`
-			Price24hPcnt string `json:"price24hPcnt"` // This is a decimal (e.g., "0.0123" means 1.23%)
-		} `json:"list"`
+		List []bybitTicker `json:"list"`
 	} `json:"result"`
 }
 
-func (b *BybitProvider) GetPriceAndChange(symbol string) (price float64, changePct24h float64, err error) {
+func (b *BybitProvider) GetTicker(ctx context.Context, symbol string) (*Ticker, error) {
 	if symbol == "" {
-		return 0, 0, NewProviderError(b.Name(), symbol, ErrSymbolNotSupported)
+		return nil, NewProviderError(b.Name(), symbol, ErrSymbolNotSupported)
 	}
 
+	var tickerResp bybitTickerResponse
 	url := fmt.Sprintf("%s/v5/market/tickers?category=spot&symbol=%s", b.baseURL, symbol)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return 0, 0, NewProviderError(b.Name(), symbol, err)
+	if err := getJSON(ctx, b.client, b.limiter, observeBybitLimit, b.Name(), symbol, bybitError, url, &tickerResp); err != nil {
+		return nil, err
 	}
 
-	req.Header.Set("User-Agent", DefaultUserAgent())
-
-	resp, err := b.client.Do(req)
-	if err != nil {
-		return 0, 0, NewProviderError(b.Name(), symbol, err)
+	// Bybit returns retCode 0 for success
+	if tickerResp.RetCode != 0 {
+		err := newAPIError(bybitErrorKinds, http.StatusOK, strconv.Itoa(tickerResp.RetCode), tickerResp.RetMsg)
+		b.limiter.ObserveError(err)
+		return nil, NewProviderError(b.Name(), symbol, err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusTooManyRequests {
-		return 0, 0, NewProviderError(b.Name(), symbol, ErrRateLimited)
+	if len(tickerResp.Result.List) == 0 {
+		return nil, NewProviderError(b.Name(), symbol, fmt.Errorf("no data returned"))
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return 0, 0, NewProviderError(b.Name(), symbol, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body)))
+	t, err := tickerResp.Result.List[0].ticker()
+	if err != nil {
+		return nil, NewProviderError(b.Name(), symbol, err)
 	}
+	return t, nil
+}
 
+// GetTickers fetches all spot tickers in one request and keeps those of symbols
+func (b *BybitProvider) GetTickers(ctx context.Context, symbols []string) (map[string]*Ticker, error) {
 	var tickerResp bybitTickerResponse
-	if err := json.NewDecoder(resp.Body).Decode(&tickerResp); err != nil {
-		return 0, 0, NewProviderError(b.Name(), symbol, err)
+	if err := getJSON(ctx, b.client, b.limiter, observeBybitLimit, b.Name(), "", bybitError, b.baseURL+"/v5/market/tickers?category=spot", &tickerResp); err != nil {
+		return nil, err
 	}
 
-	// Bybit returns retCode 0 for success
 	if tickerResp.RetCode != 0 {
-		return 0, 0, NewProviderError(b.Name(), symbol, fmt.Errorf("Bybit API error: %s (code %d)", tickerResp.RetMsg, tickerResp.RetCode))
+		err := newAPIError(bybitErrorKinds, http.StatusOK, strconv.Itoa(tickerResp.RetCode), tickerResp.RetMsg)
+		b.limiter.ObserveError(err)
+		return nil, NewProviderError(b.Name(), "", err)
 	}
 
-	if len(tickerResp.Result.List) == 0 {
-		return 0, 0, NewProviderError(b.Name(), symbol, fmt.Errorf("no data returned"))
+	wanted := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		wanted[symbol] = true
 	}
 
-	ticker := tickerResp.Result.List[0]
+	result := make(map[string]*Ticker, len(symbols))
+	for _, ticker := range tickerResp.Result.List {
+		if !wanted[ticker.Symbol] {
+			continue
+		}
+		if t, err := ticker.ticker(); err == nil {
+			result[ticker.Symbol] = t
+		}
+	}
+	return result, nil
+}
 
-	price, err = strconv.ParseFloat(ticker.LastPrice, 64)
+func (t bybitTicker) ticker() (*Ticker, error) {
+	price, err := strconv.ParseFloat(t.LastPrice, 64)
 	if err != nil {
-		return 0, 0, NewProviderError(b.Name(), symbol, fmt.Errorf("parse lastPrice: %w", err))
+		return nil, fmt.Errorf("parse lastPrice: %w", err)
 	}
 
 	// price24hPcnt is in decimal form (e.g., "0.0123" = 1.23%), multiply by 100 to get percentage
-	changePctDecimal, err := strconv.ParseFloat(ticker.Price24hPcnt, 64)
+	changePctDecimal, err := strconv.ParseFloat(t.Price24hPcnt, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parse price24hPcnt: %w", err)
+	}
+
+	turnover24h, err := strconv.ParseFloat(t.Turnover24h, 64)
 	if err != nil {
-		return 0, 0, NewProviderError(b.Name(), symbol, fmt.Errorf("parse price24hPcnt: %w", err))
+		return nil, fmt.Errorf("parse turnover24h: %w", err)
 	}
 
-	changePct24h = changePctDecimal * 100
+	return &Ticker{Price: price, ChangePct24h: changePctDecimal * 100, QuoteVolume24h: turnover24h}, nil
+}
 
-	return price, changePct24h, nil
+type bybitInstrumentsResponse struct {
+	RetCode int    `json:"retCode"`
+	RetMsg  string `json:"retMsg"`
+	Result  struct {
+		List []struct {
+			Symbol    string `json:"symbol"`
+			BaseCoin  string `json:"baseCoin"`
+			QuoteCoin string `json:"quoteCoin"`
+			Status    string `json:"status"`
+		} `json:"list"`
+	} `json:"result"`
+}
+
+// ListInstruments returns the spot pairs currently trading on Bybit
+func (b *BybitProvider) ListInstruments(ctx context.Context) ([]Instrument, error) {
+	var instResp bybitInstrumentsResponse
+	if err := getJSON(ctx, b.client, b.limiter, observeBybitLimit, b.Name(), "", bybitError, b.baseURL+"/v5/market/instruments-info?category=spot", &instResp); err != nil {
+		return nil, err
+	}
+
+	if instResp.RetCode != 0 {
+		return nil, NewProviderError(b.Name(), "", newAPIError(bybitErrorKinds, http.StatusOK, strconv.Itoa(instResp.RetCode), instResp.RetMsg))
+	}
+
+	instruments := make([]Instrument, 0, len(instResp.Result.List))
+	for _, inst := range instResp.Result.List {
+		if inst.Status == "Trading" {
+			instruments = append(instruments, Instrument{Symbol: inst.Symbol, Base: inst.BaseCoin, Quote: inst.QuoteCoin})
+		}
+	}
+	return instruments, nil
+}
+
+// bybitSubscribeLimit is the most topics Bybit accepts in one spot subscribe request
+const bybitSubscribeLimit = 10
+
+// TickerStream subscribes to the tickers.<symbol> topics; Bybit expects an
+// {"op":"ping"} every 20 seconds
+func (b *BybitProvider) TickerStream(symbols []string) Stream {
+	var subscribe []any
+	for _, batch := range chunk(symbols, bybitSubscribeLimit) {
+		topics := make([]string, 0, len(batch))
+		for _, symbol := range batch {
+			topics = append(topics, "tickers."+symbol)
+		}
+		subscribe = append(subscribe, map[string]any{"op": "subscribe", "args": topics})
+	}
+
+	return Stream{
+		URL:          b.wsURL,
+		Subscribe:    subscribe,
+		Ping:         []byte(`{"op":"ping"}`),
+		PingInterval: 20 * time.Second,
+		Parse: func(msg []byte) map[string]*Ticker {
+			var push struct {
+				Topic string      `json:"topic"`
+				Data  bybitTicker `json:"data"`
+			}
+			if err := json.Unmarshal(msg, &push); err != nil || !strings.HasPrefix(push.Topic, "tickers.") {
+				return nil
+			}
+			t, err := push.Data.ticker()
+			if err != nil {
+				return nil
+			}
+			return map[string]*Ticker{push.Data.Symbol: t}
+		},
+	}
 }
@@ -0,0 +1,49 @@
+package exchanges
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// maintenanceBackoff is how long a provider is suspended after reporting maintenance
+const maintenanceBackoff = 5 * time.Minute
+
+// APIError is an error payload returned by an exchange, classified into one of
+// ErrSymbolNotSupported, ErrRateLimited, ErrMaintenance, ErrBadRequest or ErrServer
+type APIError struct {
+	Kind       error
+	StatusCode int
+	Code       string
+	Msg        string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%v (HTTP %d, code %s: %s)", e.Kind, e.StatusCode, e.Code, e.Msg)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Kind
+}
+
+// newAPIError classifies an exchange error code using kinds, falling back to the HTTP status
+func newAPIError(kinds map[string]error, status int, code, msg string) *APIError {
+	kind, ok := kinds[code]
+	if !ok {
+		kind = statusKind(status)
+	}
+	return &APIError{Kind: kind, StatusCode: status, Code: code, Msg: msg}
+}
+
+// statusKind classifies an HTTP status code; a 200 carrying an error payload
+// counts as a bad request
+func statusKind(status int) error {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case status >= 500:
+		return ErrServer
+	default:
+		return ErrBadRequest
+	}
+}
@@ -0,0 +1,15 @@
+package exchanges
+
+import "context"
+
+// Instrument is a spot trading pair listed on an exchange
+type Instrument struct {
+	Symbol string // Symbol as passed to GetTicker, e.g. "SCRUSDT" or "SCR-USDT"
+	Base   string // Base asset, e.g. "SCR"
+	Quote  string // Quote asset, e.g. "USDT"
+}
+
+// InstrumentLister is implemented by providers that can list their tradable spot pairs
+type InstrumentLister interface {
+	ListInstruments(ctx context.Context) ([]Instrument, error)
+}
@@ -0,0 +1,84 @@
+package exchanges
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultRateLimitBackoff is how long a provider is suspended after a 429 without Retry-After
+const defaultRateLimitBackoff = time.Minute
+
+// Limiter tracks whether a provider has been told to back off, based on HTTP
+// status codes and rate-limit headers from its responses
+type Limiter struct {
+	name string
+
+	mu    sync.Mutex
+	until time.Time
+}
+
+// NewLimiter creates a limiter for the named provider
+func NewLimiter(name string) *Limiter {
+	return &Limiter{name: name}
+}
+
+// SuspendedUntil returns when the provider may be called again; a past or zero
+// time means it isn't suspended
+func (l *Limiter) SuspendedUntil() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.until
+}
+
+// Suspend stops calls to the provider until the given time. An existing longer
+// suspension is kept.
+func (l *Limiter) Suspend(until time.Time, reason string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !until.After(l.until) {
+		return
+	}
+	l.until = until
+	slog.Warn("Provider suspended", "provider", l.name, "until", until, "reason", reason)
+}
+
+// Observe inspects a response for rate limiting: 429 (and Binance's 418 ban)
+// suspend the provider for Retry-After, or a default backoff without it
+func (l *Limiter) Observe(resp *http.Response) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusTeapot {
+		return
+	}
+
+	now := time.Now()
+	wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now)
+	if !ok {
+		wait = defaultRateLimitBackoff
+	}
+	l.Suspend(now.Add(wait), "HTTP "+strconv.Itoa(resp.StatusCode))
+}
+
+// ObserveError suspends the provider when the exchange reports maintenance
+func (l *Limiter) ObserveError(err error) {
+	if errors.Is(err, ErrMaintenance) {
+		l.Suspend(time.Now().Add(maintenanceBackoff), "maintenance")
+	}
+}
+
+// parseRetryAfter parses a Retry-After value given in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return at.Sub(now), true
+	}
+	return 0, false
+}
@@ -1,9 +1,9 @@
 package exchanges
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"strconv"
 	"time"
@@ -12,12 +12,20 @@ import (
 type OKXProvider struct {
 	client  *http.Client
 	baseURL string
+	wsURL   string
+	limiter *Limiter
+}
+
+func init() {
+	Register("okx", "OKX", func(timeout time.Duration) Provider { return NewOKXProvider(timeout) })
 }
 
 func NewOKXProvider(timeout time.Duration) *OKXProvider {
 	return &OKXProvider{
 		client:  SharedHTTPClient(timeout),
+		limiter: NewLimiter("okx"),
 		baseURL: "https://www.okx.com",
+		wsURL:   "wss://ws.okx.com:8443/ws/v5/public",
 	}
 }
 
@@ -25,74 +33,191 @@ func (o *OKXProvider) Name() string {
 	return "okx"
 }
 
+func (o *OKXProvider) SuspendedUntil() time.Time {
+	return o.limiter.SuspendedUntil()
+}
+
+// okxErrorKinds classifies OKX error codes
+var okxErrorKinds = map[string]error{
+	"51001": ErrSymbolNotSupported, // Instrument ID does not exist
+	"51000": ErrBadRequest,         // Parameter error
+	"50011": ErrRateLimited,        // Rate limit reached
+	"50061": ErrRateLimited,        // Sub-account rate limit reached
+	"50001": ErrMaintenance,        // Service temporarily unavailable
+	"50004": ErrServer,             // Endpoint request timeout
+	"50013": ErrServer,             // Systems are busy
+	"50026": ErrServer,             // System error
+}
+
+// okxError classifies a non-200 OKX response by its {"code","msg"} payload
+func okxError(status int, body []byte) error {
+	var payload struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || payload.Code == "" || payload.Code == "0" {
+		return &HTTPError{StatusCode: status, Body: string(body)}
+	}
+	return newAPIError(okxErrorKinds, status, payload.Code, payload.Msg)
+}
+
+type okxTicker struct {
+	InstID  string `json:"instId"`
+	Last    string `json:"last"`
+	Open24h string `json:"open24h"`
+	// VolCcy24h is the 24h volume in the quote currency for spot pairs
+	VolCcy24h string `json:"volCcy24h"`
+}
+
 type okxTickerResponse struct {
-	Code string `json:"code"`
-	Data []struct {
-		Last    string `json:"last"`
-		Open24h string `json:"open24h"`
-	} `json:"data"`
+	Code string      `json:"code"`
+	Msg  string      `json:"msg"`
+	Data []okxTicker `json:"data"`
 }
 
-func (o *OKXProvider) GetPriceAndChange(symbol string) (price float64, changePct24h float64, err error) {
+func (o *OKXProvider) GetTicker(ctx context.Context, symbol string) (*Ticker, error) {
 	if symbol == "" {
-		return 0, 0, NewProviderError(o.Name(), symbol, ErrSymbolNotSupported)
+		return nil, NewProviderError(o.Name(), symbol, ErrSymbolNotSupported)
 	}
 
+	var tickerResp okxTickerResponse
 	url := fmt.Sprintf("%s/api/v5/market/ticker?instId=%s", o.baseURL, symbol)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return 0, 0, NewProviderError(o.Name(), symbol, err)
+	if err := getJSON(ctx, o.client, o.limiter, nil, o.Name(), symbol, okxError, url, &tickerResp); err != nil {
+		return nil, err
 	}
 
-	req.Header.Set("User-Agent", DefaultUserAgent())
-
-	resp, err := o.client.Do(req)
-	if err != nil {
-		return 0, 0, NewProviderError(o.Name(), symbol, err)
+	// OKX returns code "0" for success
+	if tickerResp.Code != "0" {
+		err := newAPIError(okxErrorKinds, http.StatusOK, tickerResp.Code, tickerResp.Msg)
+		o.limiter.ObserveError(err)
+		return nil, NewProviderError(o.Name(), symbol, err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusTooManyRequests {
-		return 0, 0, NewProviderError(o.Name(), symbol, ErrRateLimited)
+	if len(tickerResp.Data) == 0 {
+		return nil, NewProviderError(o.Name(), symbol, fmt.Errorf("no data returned"))
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return 0, 0, NewProviderError(o.Name(), symbol, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body)))
+	t, err := tickerResp.Data[0].ticker()
+	if err != nil {
+		return nil, NewProviderError(o.Name(), symbol, err)
 	}
+	return t, nil
+}
 
+// GetTickers fetches all spot tickers in one request and keeps those of symbols
+func (o *OKXProvider) GetTickers(ctx context.Context, symbols []string) (map[string]*Ticker, error) {
 	var tickerResp okxTickerResponse
-	if err := json.NewDecoder(resp.Body).Decode(&tickerResp); err != nil {
-		return 0, 0, NewProviderError(o.Name(), symbol, err)
+	if err := getJSON(ctx, o.client, o.limiter, nil, o.Name(), "", okxError, o.baseURL+"/api/v5/market/tickers?instType=SPOT", &tickerResp); err != nil {
+		return nil, err
 	}
 
-	// OKX returns code "0" for success
 	if tickerResp.Code != "0" {
-		return 0, 0, NewProviderError(o.Name(), symbol, fmt.Errorf("OKX API error code: %s", tickerResp.Code))
+		err := newAPIError(okxErrorKinds, http.StatusOK, tickerResp.Code, tickerResp.Msg)
+		o.limiter.ObserveError(err)
+		return nil, NewProviderError(o.Name(), "", err)
 	}
 
-	if len(tickerResp.Data) == 0 {
-		return 0, 0, NewProviderError(o.Name(), symbol, fmt.Errorf("no data returned"))
+	wanted := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		wanted[symbol] = true
 	}
 
-	ticker := tickerResp.Data[0]
+	result := make(map[string]*Ticker, len(symbols))
+	for _, ticker := range tickerResp.Data {
+		if !wanted[ticker.InstID] {
+			continue
+		}
+		if t, err := ticker.ticker(); err == nil {
+			result[ticker.InstID] = t
+		}
+	}
+	return result, nil
+}
 
-	last, err := strconv.ParseFloat(ticker.Last, 64)
+func (t okxTicker) ticker() (*Ticker, error) {
+	last, err := strconv.ParseFloat(t.Last, 64)
 	if err != nil {
-		return 0, 0, NewProviderError(o.Name(), symbol, fmt.Errorf("parse last: %w", err))
+		return nil, fmt.Errorf("parse last: %w", err)
 	}
 
-	open24h, err := strconv.ParseFloat(ticker.Open24h, 64)
+	open24h, err := strconv.ParseFloat(t.Open24h, 64)
 	if err != nil {
-		return 0, 0, NewProviderError(o.Name(), symbol, fmt.Errorf("parse open24h: %w", err))
+		return nil, fmt.Errorf("parse open24h: %w", err)
 	}
 
 	if open24h == 0 {
-		return 0, 0, NewProviderError(o.Name(), symbol, fmt.Errorf("open24h is zero, cannot calculate change"))
+		return nil, fmt.Errorf("open24h is zero, cannot calculate change")
 	}
 
 	// Calculate 24h change percentage: (last/open24h - 1) * 100
-	changePct24h = (last/open24h - 1) * 100
+	changePct24h := (last/open24h - 1) * 100
+
+	volCcy24h, err := strconv.ParseFloat(t.VolCcy24h, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parse volCcy24h: %w", err)
+	}
+
+	return &Ticker{Price: last, ChangePct24h: changePct24h, QuoteVolume24h: volCcy24h}, nil
+}
+
+type okxInstrumentsResponse struct {
+	Code string `json:"code"`
+	Msg  string `json:"msg"`
+	Data []struct {
+		InstID   string `json:"instId"`
+		BaseCcy  string `json:"baseCcy"`
+		QuoteCcy string `json:"quoteCcy"`
+		State    string `json:"state"`
+	} `json:"data"`
+}
+
+// ListInstruments returns the spot pairs currently live on OKX
+func (o *OKXProvider) ListInstruments(ctx context.Context) ([]Instrument, error) {
+	var instResp okxInstrumentsResponse
+	if err := getJSON(ctx, o.client, o.limiter, nil, o.Name(), "", okxError, o.baseURL+"/api/v5/public/instruments?instType=SPOT", &instResp); err != nil {
+		return nil, err
+	}
+
+	if instResp.Code != "0" {
+		return nil, NewProviderError(o.Name(), "", newAPIError(okxErrorKinds, http.StatusOK, instResp.Code, instResp.Msg))
+	}
 
-	return last, changePct24h, nil
+	instruments := make([]Instrument, 0, len(instResp.Data))
+	for _, inst := range instResp.Data {
+		if inst.State == "live" {
+			instruments = append(instruments, Instrument{Symbol: inst.InstID, Base: inst.BaseCcy, Quote: inst.QuoteCcy})
+		}
+	}
+	return instruments, nil
+}
+
+// TickerStream subscribes to the tickers channel of each symbol; OKX drops
+// connections that stay silent for 30 seconds, so a "ping" is sent every 20
+func (o *OKXProvider) TickerStream(symbols []string) Stream {
+	args := make([]map[string]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		args = append(args, map[string]string{"channel": "tickers", "instId": symbol})
+	}
+
+	return Stream{
+		URL:          o.wsURL,
+		Subscribe:    []any{map[string]any{"op": "subscribe", "args": args}},
+		Ping:         []byte("ping"),
+		PingInterval: 20 * time.Second,
+		Parse: func(msg []byte) map[string]*Ticker {
+			var push struct {
+				Data []okxTicker `json:"data"`
+			}
+			if err := json.Unmarshal(msg, &push); err != nil {
+				return nil
+			}
+			tickers := make(map[string]*Ticker, len(push.Data))
+			for _, ticker := range push.Data {
+				if t, err := ticker.ticker(); err == nil {
+					tickers[ticker.InstID] = t
+				}
+			}
+			return tickers
+		},
+	}
 }
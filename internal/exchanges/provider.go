@@ -1,6 +1,7 @@
 package exchanges
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -10,13 +11,32 @@ import (
 // Provider defines the interface for exchange data providers
 type Provider interface {
 	Name() string
-	GetPriceAndChange(symbol string) (price float64, changePct24h float64, err error)
+	GetTicker(ctx context.Context, symbol string) (*Ticker, error)
+	// SuspendedUntil returns when the exchange may be called again after rate limiting
+	SuspendedUntil() time.Time
 }
 
-// Common errors
+// Ticker is an exchange's 24h ticker for one trading pair
+type Ticker struct {
+	Price          float64 // Last traded price
+	ChangePct24h   float64 // 24h price change in percent
+	QuoteVolume24h float64 // 24h traded volume in the quote currency (USDT for our pairs)
+}
+
+// BatchTicker is implemented by providers that can fetch many tickers in one request
+type BatchTicker interface {
+	// GetTickers returns the tickers of symbols keyed by symbol; symbols the
+	// exchange doesn't list are absent
+	GetTickers(ctx context.Context, symbols []string) (map[string]*Ticker, error)
+}
+
+// Common errors; exchange error payloads and HTTP statuses are classified into these
 var (
 	ErrSymbolNotSupported = errors.New("symbol not supported by this exchange")
 	ErrRateLimited        = errors.New("rate limited by exchange")
+	ErrMaintenance        = errors.New("exchange under maintenance")
+	ErrBadRequest         = errors.New("request rejected by exchange")
+	ErrServer             = errors.New("exchange server error")
 )
 
 // ProviderError wraps errors with context about the provider and symbol
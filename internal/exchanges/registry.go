@@ -0,0 +1,81 @@
+package exchanges
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Factory creates a provider whose requests time out after timeout
+type Factory func(timeout time.Duration) Provider
+
+// registration is a registered provider kind
+type registration struct {
+	displayName string
+	factory     Factory
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]registration)
+)
+
+// Register makes a provider available under name, which must match its Name(),
+// and shows it as displayName in reports. Providers register themselves from init;
+// registering a name twice panics.
+func Register(name, displayName string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, dup := registry[name]; dup {
+		panic(fmt.Sprintf("exchanges: provider %q registered twice", name))
+	}
+	registry[name] = registration{displayName: displayName, factory: factory}
+}
+
+// Registered reports whether a provider is registered under name
+func Registered(name string) bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	_, ok := registry[name]
+	return ok
+}
+
+// DisplayName returns the display name registered for name, or name itself if
+// no provider is registered under it
+func DisplayName(name string) string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	if r, ok := registry[name]; ok {
+		return r.displayName
+	}
+	return name
+}
+
+// Names returns the registered provider names, sorted
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewProviders creates one provider of every registered kind, keyed by name
+func NewProviders(timeout time.Duration) map[string]Provider {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	providers := make(map[string]Provider, len(registry))
+	for name, r := range registry {
+		providers[name] = r.factory(timeout)
+	}
+	return providers
+}
@@ -0,0 +1,48 @@
+package exchanges
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// getJSON performs a GET request against an exchange and decodes a successful
+// response into out. Responses go through the limiter (and observe, if set, for
+// exchange-specific rate-limit headers); classify turns a non-200 response into an
+// error. Errors name symbol, which is empty for requests not about one symbol.
+func getJSON(ctx context.Context, client *http.Client, limiter *Limiter, observe func(*Limiter, http.Header), name, symbol string, classify func(status int, body []byte) error, url string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return NewProviderError(name, symbol, err)
+	}
+
+	req.Header.Set("User-Agent", DefaultUserAgent())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return NewProviderError(name, symbol, err)
+	}
+	defer resp.Body.Close()
+
+	limiter.Observe(resp)
+	if observe != nil {
+		observe(limiter, resp.Header)
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return NewProviderError(name, symbol, ErrRateLimited)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		err := classify(resp.StatusCode, body)
+		limiter.ObserveError(err)
+		return NewProviderError(name, symbol, err)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return NewProviderError(name, symbol, err)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package exchanges
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// HTTPError is returned when an upstream answers with an unexpected status code
+type HTTPError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Body)
+}
+
+// Unwrap classifies the status code as ErrRateLimited, ErrServer or ErrBadRequest
+func (e *HTTPError) Unwrap() error {
+	return statusKind(e.StatusCode)
+}
+
+// RetryPolicy retries transient upstream failures with jittered exponential backoff
+type RetryPolicy struct {
+	MaxAttempts int           // Total attempts including the first; 1 disables retries
+	BaseDelay   time.Duration // Backoff ceiling for the first retry, doubled on each further one
+	MaxDelay    time.Duration // Upper bound for the backoff ceiling
+}
+
+// Do calls fn until it succeeds, fails permanently, runs out of attempts or ctx
+// is done. It returns the number of attempts made and the last error. op names
+// the operation in logs.
+func (p RetryPolicy) Do(ctx context.Context, op string, fn func(ctx context.Context) error) (int, error) {
+	attempts := 0
+	for {
+		attempts++
+		err := fn(ctx)
+		if err == nil || attempts >= p.MaxAttempts || !IsRetryable(err) {
+			return attempts, err
+		}
+
+		delay := p.backoff(attempts)
+		slog.Debug("Retrying request", "op", op, "attempt", attempts, "delay", delay, "error", err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return attempts, err
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns a random delay in [0, min(MaxDelay, BaseDelay*2^(attempt-1))] ("full jitter")
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	ceiling := p.BaseDelay << (attempt - 1)
+	if ceiling > p.MaxDelay || ceiling <= 0 {
+		ceiling = p.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// IsRetryable reports whether err is a transient failure worth retrying: network
+// errors, connection resets, truncated responses, 5xx/408 statuses and exchange
+// server errors. Rate limits, unsupported symbols, maintenance, rejected requests
+// and cancellation are permanent.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, ErrRateLimited) || errors.Is(err, ErrSymbolNotSupported) {
+		return false
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500 || httpErr.StatusCode == http.StatusRequestTimeout
+	}
+
+	// Classified exchange error payloads: only server-side failures are worth retrying
+	if errors.Is(err, ErrServer) {
+		return true
+	}
+	if errors.Is(err, ErrMaintenance) || errors.Is(err, ErrBadRequest) {
+		return false
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
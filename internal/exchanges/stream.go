@@ -0,0 +1,37 @@
+package exchanges
+
+import "time"
+
+// Stream describes an exchange's public ticker WebSocket for a set of symbols
+type Stream struct {
+	URL       string
+	Subscribe []any // Messages sent as JSON right after connecting
+
+	// Ping is a text heartbeat sent every PingInterval; nil if the exchange pings us
+	// with WebSocket ping frames instead
+	Ping         []byte
+	PingInterval time.Duration
+
+	// Parse returns the tickers in a message keyed by symbol; heartbeat replies and
+	// subscription acknowledgements yield none
+	Parse func(msg []byte) map[string]*Ticker
+}
+
+// Streamer is implemented by providers with a public ticker WebSocket
+type Streamer interface {
+	TickerStream(symbols []string) Stream
+}
+
+// chunk splits symbols into groups of at most size, for exchanges that cap the
+// topics of one subscribe message
+func chunk(symbols []string, size int) [][]string {
+	var chunks [][]string
+	for len(symbols) > size {
+		chunks = append(chunks, symbols[:size])
+		symbols = symbols[size:]
+	}
+	if len(symbols) > 0 {
+		chunks = append(chunks, symbols)
+	}
+	return chunks
+}
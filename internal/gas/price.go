@@ -1,46 +1,65 @@
 package gas
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"scroll-rank-bot/internal/exchanges"
+	"scroll-rank-bot/internal/metrics"
 	"scroll-rank-bot/internal/models"
 )
 
 type PriceService struct {
 	httpClient *http.Client
-	endpoints  map[string]string
+	retry      exchanges.RetryPolicy
+	networks   []models.GasNetwork
+	mu         sync.RWMutex
 }
 
-func NewPriceService() *PriceService {
+func NewPriceService(timeout time.Duration, networks []models.GasNetwork, retry exchanges.RetryPolicy) *PriceService {
 	return &PriceService{
 		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
-		endpoints: map[string]string{
-			"ethereum": "https://rpc.mevblocker.io",
-			"zksync":   "https://mainnet.era.zksync.io",
-			"taiko":    "https://rpc.mainnet.taiko.xyz",
-			"scroll":   "https://rpc.scroll.io",
-			"linea":    "https://rpc.linea.build",
+			Timeout: timeout,
 		},
+		retry:    retry,
+		networks: networks,
 	}
 }
 
-func (s *PriceService) FetchAllPrices() map[string]float64 {
+// Networks returns the configured networks in display order
+func (s *PriceService) Networks() []models.GasNetwork {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.networks
+}
+
+// SetNetworks atomically replaces the configured networks
+func (s *PriceService) SetNetworks(networks []models.GasNetwork) {
+	s.mu.Lock()
+	s.networks = networks
+	s.mu.Unlock()
+}
+
+// FetchAllPrices returns gas prices in Gwei keyed by network ID
+func (s *PriceService) FetchAllPrices(ctx context.Context) map[string]float64 {
+	networks := s.Networks()
 	results := make(chan struct {
 		network string
 		price   float64
-	}, len(s.endpoints))
+	}, len(networks))
 
 	var wg sync.WaitGroup
-	for network, endpoint := range s.endpoints {
+	for _, network := range networks {
 		wg.Add(1)
-		go s.fetchSinglePrice(network, endpoint, results, &wg)
+		go s.fetchSinglePrice(ctx, network.ID, network.RPC, results, &wg)
 	}
 
 	go func() {
@@ -56,12 +75,38 @@ func (s *PriceService) FetchAllPrices() map[string]float64 {
 	return prices
 }
 
-func (s *PriceService) fetchSinglePrice(network, endpoint string, results chan<- struct {
+func (s *PriceService) fetchSinglePrice(ctx context.Context, network, endpoint string, results chan<- struct {
 	network string
 	price   float64
 }, wg *sync.WaitGroup) {
 	defer wg.Done()
 
+	start := time.Now()
+	var gweiPrice float64
+	attempts, err := s.retry.Do(ctx, "gas:"+network, func(ctx context.Context) error {
+		var err error
+		gweiPrice, err = s.requestGasPrice(ctx, endpoint)
+		return err
+	})
+
+	latency := time.Since(start)
+	outcome := metrics.Outcome(err)
+	metrics.GasDuration.WithLabelValues(network).Observe(latency.Seconds())
+	metrics.GasRequests.WithLabelValues(network, outcome).Inc()
+	metrics.Retries.WithLabelValues("gas:" + network).Add(float64(attempts - 1))
+	if err != nil {
+		slog.Warn("Gas price fetch failed", "network", network, "outcome", outcome, "attempts", attempts, "latency", latency, "error", err)
+		gweiPrice = 0
+	}
+
+	results <- struct {
+		network string
+		price   float64
+	}{network: network, price: gweiPrice}
+}
+
+// requestGasPrice calls eth_gasPrice on endpoint and returns the price in Gwei
+func (s *PriceService) requestGasPrice(ctx context.Context, endpoint string) (float64, error) {
 	reqBody := models.RPCRequest{
 		JsonRPC: "2.0",
 		Method:  "eth_gasPrice",
@@ -70,33 +115,34 @@ func (s *PriceService) fetchSinglePrice(network, endpoint string, results chan<-
 	}
 
 	jsonData, _ := json.Marshal(reqBody)
-	resp, err := s.httpClient.Post(endpoint, "application/json", strings.NewReader(string(jsonData)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(string(jsonData)))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
 	if err != nil {
-		results <- struct {
-			network string
-			price   float64
-		}{network: network, price: 0}
-		return
+		return 0, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return 0, &exchanges.HTTPError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
 	var result struct {
 		Result string `json:"result"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		results <- struct {
-			network string
-			price   float64
-		}{network: network, price: 0}
-		return
+		return 0, fmt.Errorf("decode response: %w", err)
 	}
 
 	hexValue := strings.TrimPrefix(result.Result, "0x")
-	intValue, _ := strconv.ParseInt(hexValue, 16, 64)
-	gweiPrice := float64(intValue) / 1e9
-
-	results <- struct {
-		network string
-		price   float64
-	}{network: network, price: gweiPrice}
+	intValue, err := strconv.ParseInt(hexValue, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse result %q: %w", result.Result, err)
+	}
+	return float64(intValue) / 1e9, nil
 }
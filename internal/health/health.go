@@ -0,0 +1,52 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Check is the outcome of a single readiness condition
+type Check struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Report aggregates readiness checks; the service is ready only if every check passes
+type Report struct {
+	Ready  bool    `json:"ready"`
+	Checks []Check `json:"checks"`
+}
+
+// Add appends a check and updates Ready accordingly
+func (r *Report) Add(check Check) {
+	if len(r.Checks) == 0 {
+		r.Ready = true
+	}
+	r.Checks = append(r.Checks, check)
+	r.Ready = r.Ready && check.OK
+}
+
+// Checker reports the current readiness of the service
+type Checker interface {
+	Readiness() Report
+}
+
+// Register mounts /healthz and /readyz on mux.
+// /healthz only reports that the process is serving; /readyz returns 503 unless every check passes.
+func Register(mux *http.ServeMux, checker Checker) {
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte("ok\n"))
+	})
+
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		report := checker.Readiness()
+
+		w.Header().Set("Content-Type", "application/json")
+		if !report.Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(report)
+	})
+}
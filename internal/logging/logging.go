@@ -0,0 +1,43 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// Log output formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// NewHandler returns a slog handler writing to w at the given level ("debug", "info", "warn", "error")
+// in the given format ("text" or "json")
+func NewHandler(w io.Writer, level, format string) (slog.Handler, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q: %w", level, err)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch format {
+	case FormatText:
+		return slog.NewTextHandler(w, opts), nil
+	case FormatJSON:
+		return slog.NewJSONHandler(w, opts), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q, want %q or %q", format, FormatText, FormatJSON)
+	}
+}
+
+// Setup installs a stderr handler with the given level and format as the default logger
+func Setup(level, format string) error {
+	handler, err := NewHandler(os.Stderr, level, format)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
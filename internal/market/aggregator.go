@@ -1,82 +1,314 @@
 package market
 
 import (
+	"context"
 	"errors"
-	"fmt"
-	"log"
+	"log/slog"
+	"sort"
 	"sync"
 	"time"
 
 	"scroll-rank-bot/internal/coingecko"
 	"scroll-rank-bot/internal/exchanges"
+	"scroll-rank-bot/internal/metrics"
 	"scroll-rank-bot/internal/models"
+	"scroll-rank-bot/internal/stream"
 )
 
-// Aggregator fetches coin data from CoinGecko (primary) or exchanges (fallback)
+// Aggregator fetches coin data from CoinGecko (primary, batched) or exchanges (fallback)
 type Aggregator struct {
-	coingecko *coingecko.Client
-	providers []exchanges.Provider
-	supplies  map[string]models.SupplySnapshot
-	mu        sync.RWMutex
-	supplyTTL time.Duration
-	volumeTTL time.Duration
-}
-
-// NewAggregator creates a new market data aggregator
-func NewAggregator(cgClient *coingecko.Client, httpTimeout time.Duration, supplyTTL, volumeTTL time.Duration) *Aggregator {
-	return &Aggregator{
-		coingecko: cgClient,
-		providers: []exchanges.Provider{
-			exchanges.NewBinanceProvider(httpTimeout),
-			exchanges.NewOKXProvider(httpTimeout),
-			exchanges.NewBybitProvider(httpTimeout),
-			exchanges.NewBitgetProvider(httpTimeout),
-		},
-		supplies:  make(map[string]models.SupplySnapshot),
-		supplyTTL: supplyTTL,
-		volumeTTL: volumeTTL,
+	coingecko     *coingecko.Client
+	breaker       *CircuitBreaker
+	providers     map[string]exchanges.Provider
+	symbols       map[string]models.ExchangeSymbols
+	order         map[string][]string
+	outcomes      map[string]string // last request outcome per provider, as a metrics label
+	supplies      map[string]models.SupplySnapshot
+	lastGood      map[string]*models.CoinData
+	lastGoodGrace time.Duration
+	mu            sync.RWMutex
+	supplyTTL     time.Duration
+	volumeTTL     time.Duration
+	retry         exchanges.RetryPolicy
+
+	fallbackMode string
+	maxDeviation float64
+	hedgeDelay   time.Duration
+
+	streams      *stream.Manager // nil unless streaming is enabled
+	streamMaxAge time.Duration
+
+	cacheFile string
+	persistMu sync.Mutex
+}
+
+// NewAggregator creates a new market data aggregator, restoring persisted supply
+// snapshots from opts.CacheFile if present
+func NewAggregator(cgClient *coingecko.Client, opts Options) *Aggregator {
+	a := &Aggregator{
+		coingecko:     cgClient,
+		breaker:       NewCircuitBreaker("coingecko", opts.BreakerThreshold, opts.BreakerCooldown),
+		providers:     exchanges.NewProviders(opts.HTTPTimeout),
+		symbols:       opts.Symbols,
+		order:         opts.Order,
+		outcomes:      make(map[string]string),
+		supplies:      make(map[string]models.SupplySnapshot),
+		lastGood:      make(map[string]*models.CoinData),
+		lastGoodGrace: opts.LastGood,
+		supplyTTL:     opts.SupplyTTL,
+		volumeTTL:     opts.VolumeTTL,
+		retry:         opts.Retry,
+
+		fallbackMode: opts.FallbackMode,
+		maxDeviation: opts.MaxDeviation,
+		hedgeDelay:   opts.HedgeDelay,
+		cacheFile:    opts.CacheFile,
+
+		streamMaxAge: opts.StreamMaxAge,
+	}
+	if opts.Streaming {
+		a.streams = stream.NewManager(a.providers)
+	}
+
+	// A broken cache file only costs us the head start, so don't fail startup over it
+	if err := a.loadSupplyCache(); err != nil {
+		slog.Warn("Failed to load supply cache", "path", a.cacheFile, "error", err)
+	}
+	return a
+}
+
+// SetExchanges atomically replaces the exchange symbol mappings and per-coin exchange order
+func (a *Aggregator) SetExchanges(symbols map[string]models.ExchangeSymbols, order map[string][]string) {
+	a.mu.Lock()
+	a.symbols = symbols
+	a.order = order
+	a.mu.Unlock()
+
+	if a.streams != nil {
+		a.streams.SetSymbols(a.streamSymbols())
+	}
+}
+
+// SupplySnapshots returns a copy of the cached supply snapshots keyed by coin ID
+func (a *Aggregator) SupplySnapshots() map[string]models.SupplySnapshot {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	snapshots := make(map[string]models.SupplySnapshot, len(a.supplies))
+	for coinID, snapshot := range a.supplies {
+		snapshots[coinID] = snapshot
+	}
+	return snapshots
+}
+
+// SupplyTTL returns how long cached supply data stays valid
+func (a *Aggregator) SupplyTTL() time.Duration {
+	return a.supplyTTL
+}
+
+// BreakerState returns the state of the CoinGecko circuit breaker
+func (a *Aggregator) BreakerState() BreakerState {
+	return a.breaker.State()
+}
+
+// ProviderStatus is an exchange provider's state as shown by /status
+type ProviderStatus struct {
+	Name           string
+	LastOutcome    string    // Outcome label of the last request, empty if none was made
+	SuspendedUntil time.Time // Zero or past unless the provider is rate limited or in maintenance
+}
+
+// ProviderStatuses returns the state of every exchange provider, sorted by name
+func (a *Aggregator) ProviderStatuses() []ProviderStatus {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	statuses := make([]ProviderStatus, 0, len(a.providers))
+	for name, provider := range a.providers {
+		statuses = append(statuses, ProviderStatus{
+			Name:           name,
+			LastOutcome:    a.outcomes[name],
+			SuspendedUntil: provider.SuspendedUntil(),
+		})
 	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
 }
 
-// FetchCoinData fetches data for a coin, trying CoinGecko first, then exchanges
-func (a *Aggregator) FetchCoinData(coin models.Coin) (*models.CoinData, error) {
-	// Try CoinGecko first
-	data, err := a.fetchFromCoinGecko(coin.ID)
+// recordOutcome remembers the outcome of a provider's latest request for ProviderStatuses
+func (a *Aggregator) recordOutcome(provider, outcome string) {
+	// A canceled request says nothing about the exchange
+	if outcome == metrics.OutcomeCanceled {
+		return
+	}
+	a.mu.Lock()
+	a.outcomes[provider] = outcome
+	a.mu.Unlock()
+}
+
+// FetchRankings fetches all coins and returns them sorted by FDV, unavailable coins
+// last. CoinGecko is queried once for the whole list; coins missing from that batch
+// (or all of them if the batch fails) fall back to exchanges concurrently.
+func (a *Aggregator) FetchRankings(ctx context.Context, coins []models.Coin) []models.CoinResult {
+	ids := make([]string, 0, len(coins))
+	for _, coin := range coins {
+		ids = append(ids, coin.ID)
+	}
+
+	start := time.Now()
+	batch, err := a.fetchFromCoinGecko(ctx, ids)
+	if errors.Is(err, ErrCircuitOpen) {
+		slog.Info("CoinGecko circuit open, using exchanges", "source", "coingecko", "breaker", a.breaker.State().String())
+	} else if err != nil {
+		slog.Warn("CoinGecko batch fetch failed, trying exchanges", "source", "coingecko", "coins", len(ids), "latency", time.Since(start), "error", err)
+	} else {
+		slog.Debug("Fetched CoinGecko batch", "source", "coingecko", "coins", len(batch), "latency", time.Since(start))
+	}
+
+	// Exchange tickers for every coin CoinGecko didn't cover, one request per exchange
+	var fallback []models.Coin
+	for _, coin := range coins {
+		if _, ok := batch[coin.ID]; !ok {
+			fallback = append(fallback, coin)
+		}
+	}
+	tickers := a.newTickerBatch(ctx, fallback)
+
+	var wg sync.WaitGroup
+	results := make(chan models.CoinResult, len(coins))
+
+	for _, coin := range coins {
+		wg.Add(1)
+		go func(coin models.Coin) {
+			defer wg.Done()
+			data, err := a.fetchCoinData(ctx, coin, batch, tickers)
+			if err != nil {
+				results <- models.CoinResult{Coin: coin, Data: nil}
+				return
+			}
+			results <- models.CoinResult{Coin: coin, Data: data}
+		}(coin)
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var coinDataList []models.CoinResult
+	for result := range results {
+		coinDataList = append(coinDataList, result)
+	}
+
+	// Snapshots only change when CoinGecko answered; write them once for the whole cycle
+	if len(batch) > 0 {
+		if err := a.saveSupplyCache(); err != nil {
+			slog.Warn("Failed to persist supply cache", "path", a.cacheFile, "error", err)
+		}
+	}
+
+	sortByFDV(coinDataList)
+	return coinDataList
+}
+
+// sortByFDV sorts results by FDV, highest first, with unavailable coins last
+func sortByFDV(results []models.CoinResult) {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Data == nil || results[j].Data == nil {
+			return results[j].Data == nil && results[i].Data != nil
+		}
+		return results[i].Data.FullyDilutedValuation.USD > results[j].Data.FullyDilutedValuation.USD
+	})
+}
+
+// fetchCoinData takes a coin's data from the CoinGecko batch, or from exchanges if it's
+// missing, remembering it as the coin's last good data
+func (a *Aggregator) fetchCoinData(ctx context.Context, coin models.Coin, batch map[string]*models.CoinData, tickers *tickerBatch) (*models.CoinData, error) {
+	data, err := a.fetchLiveCoinData(ctx, coin, batch, tickers)
 	if err == nil {
+		a.mu.Lock()
+		a.lastGood[coin.ID] = data
+		a.mu.Unlock()
+		return data, nil
+	}
+
+	if stale, ok := a.lastGoodData(coin.ID, time.Now()); ok {
+		metrics.DataSource.WithLabelValues("last_good").Inc()
+		slog.Warn("All sources failed, serving last good data", "coin", coin.ID, "age", time.Since(stale.FetchedAt), "error", err)
+		return stale, nil
+	}
+
+	metrics.DataSource.WithLabelValues("none").Inc()
+	slog.Error("All sources failed", "coin", coin.ID, "error", err)
+	return nil, err
+}
+
+// lastGoodData returns a stale copy of the coin's last good data if it is within the grace period
+func (a *Aggregator) lastGoodData(coinID string, now time.Time) (*models.CoinData, bool) {
+	a.mu.RLock()
+	last, ok := a.lastGood[coinID]
+	a.mu.RUnlock()
+	if !ok || now.Sub(last.FetchedAt) >= a.lastGoodGrace {
+		return nil, false
+	}
+
+	stale := *last
+	stale.Stale = true
+	return &stale, true
+}
+
+// fetchLiveCoinData takes a coin's data from the CoinGecko batch, or from exchanges if it's missing
+func (a *Aggregator) fetchLiveCoinData(ctx context.Context, coin models.Coin, batch map[string]*models.CoinData, tickers *tickerBatch) (*models.CoinData, error) {
+	logger := slog.With("coin", coin.ID)
+
+	if data, ok := batch[coin.ID]; ok {
+		data.Sources = []string{models.SourceCoinGecko}
+		data.FetchedAt = time.Now()
+
 		// CoinGecko succeeded, cache supply and volume data
 		a.updateSupplyCache(coin.ID, data)
-		log.Printf("[%s] source=coingecko status=success", coin.ID)
+		metrics.DataSource.WithLabelValues("coingecko").Inc()
+		logger.Debug("Fetched coin data", "source", "coingecko")
 		return data, nil
 	}
 
-	// CoinGecko failed, log and try fallback
-	log.Printf("[%s] source=coingecko status=failed error=%v, trying exchanges", coin.ID, err)
+	if batch != nil {
+		logger.Warn("Coin missing from CoinGecko batch, trying exchanges", "source", "coingecko")
+	}
 
 	// Try exchanges in order
-	data, err = a.fetchFromExchanges(coin)
+	data, err := a.fetchFromExchanges(ctx, coin, tickers)
 	if err == nil {
-		log.Printf("[%s] source=exchange status=success", coin.ID)
+		metrics.DataSource.WithLabelValues("exchange").Inc()
+		logger.Info("Fetched coin data", "source", "exchange")
 		return data, nil
 	}
 
-	log.Printf("[%s] source=all status=failed error=%v", coin.ID, err)
 	return nil, err
 }
 
-// fetchFromCoinGecko fetches data from CoinGecko
-func (a *Aggregator) fetchFromCoinGecko(coinID string) (*models.CoinData, error) {
-	return a.coingecko.FetchCoinData(coinID)
+// fetchFromCoinGecko fetches market data for all coinIDs from CoinGecko in one request,
+// unless the circuit breaker is open
+func (a *Aggregator) fetchFromCoinGecko(ctx context.Context, coinIDs []string) (map[string]*models.CoinData, error) {
+	if !a.breaker.Allow() {
+		return nil, ErrCircuitOpen
+	}
+
+	start := time.Now()
+	data, err := a.coingecko.FetchMarkets(ctx, coinIDs)
+	a.breaker.Record(err)
+	metrics.CoinGeckoDuration.Observe(metrics.Since(start))
+	metrics.CoinGeckoRequests.WithLabelValues(metrics.Outcome(err)).Inc()
+	return data, err
 }
 
-// updateSupplyCache calculates and caches supply and volume data
+// updateSupplyCache calculates and caches supply and volume data; FetchRankings
+// persists the cache once per cycle
 func (a *Aggregator) updateSupplyCache(coinID string, data *models.CoinData) {
 	if data == nil {
 		return
 	}
 
-	a.mu.Lock()
-	defer a.mu.Unlock()
-
 	snapshot := models.SupplySnapshot{
 		UpdatedAt:      time.Now(),
 		TotalVolumeUSD: data.Volume24h.USD,
@@ -92,73 +324,64 @@ func (a *Aggregator) updateSupplyCache(coinID string, data *models.CoinData) {
 		}
 	}
 
+	a.mu.Lock()
 	a.supplies[coinID] = snapshot
-	log.Printf("[%s] cache_update circulating=%.2f full=%.2f volume=%.2f", coinID, snapshot.Circulating, snapshot.Full, snapshot.TotalVolumeUSD)
+	a.mu.Unlock()
+	slog.Debug("Supply cache updated", "coin", coinID, "circulating", snapshot.Circulating, "full", snapshot.Full, "volume_usd", snapshot.TotalVolumeUSD)
 }
 
-// fetchFromExchanges tries to fetch price and change from exchanges in order
-func (a *Aggregator) fetchFromExchanges(coin models.Coin) (*models.CoinData, error) {
-	exchangeSymbols, ok := models.Symbols[coin.ID]
-	if !ok {
-		return nil, fmt.Errorf("no exchange symbols configured for coin %s", coin.ID)
-	}
-
-	var lastErr error
-
-	for _, provider := range a.providers {
-		var symbol string
-		switch provider.Name() {
-		case "binance":
-			symbol = exchangeSymbols.Binance
-		case "okx":
-			symbol = exchangeSymbols.OKX
-		case "bybit":
-			symbol = exchangeSymbols.Bybit
-		case "bitget":
-			symbol = exchangeSymbols.Bitget
-		}
-
-		if symbol == "" {
-			// This exchange doesn't support this coin
-			continue
-		}
+// Cache lookup results, as supply_cache_lookups_total labels
+const (
+	lookupHit     = "hit"
+	lookupMiss    = "miss"
+	lookupExpired = "expired"
+)
 
-		price, changePct, err := provider.GetPriceAndChange(symbol)
-		if err != nil {
-			// Check if it's a "not supported" error
-			if errors.Is(err, exchanges.ErrSymbolNotSupported) {
-				log.Printf("[%s] provider=%s symbol=%s status=not_supported", coin.ID, provider.Name(), symbol)
-				continue
-			}
-			// Other errors, log and continue
-			log.Printf("[%s] provider=%s symbol=%s status=failed error=%v", coin.ID, provider.Name(), symbol, err)
-			lastErr = err
-			continue
-		}
+// composeCoinData creates CoinData from exchange price and volume + cached supply,
+// recording the cache lookups in metrics and logs. The cached CoinGecko volume is
+// only used when no exchange reported volume.
+func (a *Aggregator) composeCoinData(coinID string, result priceResult) *models.CoinData {
+	data, supply, volume := a.buildCoinData(coinID, result, time.Now())
 
-		// Success! Construct CoinData from cached supply and fetched price
-		log.Printf("[%s] provider=%s symbol=%s price=%.4f change=%.2f%%", coin.ID, provider.Name(), symbol, price, changePct)
-		return a.composeCoinData(coin.ID, price, changePct), nil
+	metrics.SupplyCacheLookups.WithLabelValues("supply", supply).Inc()
+	switch supply {
+	case lookupMiss:
+		slog.Warn("No cached supply data", "coin", coinID)
+	case lookupExpired:
+		slog.Warn("Supply cache expired", "coin", coinID)
+	default:
+		slog.Debug("Supply cache hit", "coin", coinID, "market_cap", data.MarketCap.USD, "fdv", data.FullyDilutedValuation.USD)
 	}
 
-	if lastErr != nil {
-		return nil, fmt.Errorf("all exchanges failed, last error: %w", lastErr)
+	switch volume {
+	case "":
+		slog.Debug("Using exchange volume", "coin", coinID, "volume_usd", data.Volume24h.USD)
+	case lookupHit:
+		metrics.SupplyCacheLookups.WithLabelValues("volume", volume).Inc()
+		slog.Debug("Volume cache hit", "coin", coinID, "volume_usd", data.Volume24h.USD)
+	default:
+		metrics.SupplyCacheLookups.WithLabelValues("volume", volume).Inc()
+		slog.Debug("Volume cache missing or expired", "coin", coinID, "result", volume)
 	}
-	return nil, fmt.Errorf("no supported exchange found for coin %s", coin.ID)
+
+	return data
 }
 
-// composeCoinData creates CoinData from exchange price + cached supply
-func (a *Aggregator) composeCoinData(coinID string, price, changePct float64) *models.CoinData {
+// buildCoinData combines exchange price and volume with the cached supply, without
+// side effects. supply and volume are the cache lookup results; volume is empty
+// when the exchanges reported volume and the cache wasn't consulted.
+func (a *Aggregator) buildCoinData(coinID string, result priceResult, now time.Time) (data *models.CoinData, supply, volume string) {
+	price, volumeUSD := result.price, result.volumeUSD
+
 	a.mu.RLock()
 	snapshot, exists := a.supplies[coinID]
 	a.mu.RUnlock()
 
-	now := time.Now()
-	data := &models.CoinData{
+	data = &models.CoinData{
 		Price: models.MultiCurrency{
 			USD: price,
 		},
-		PriceChangePercentage24h: changePct,
+		PriceChangePercentage24h: result.changePct,
 		MarketCap: models.MultiCurrency{
 			USD: 0,
 		},
@@ -166,35 +389,42 @@ func (a *Aggregator) composeCoinData(coinID string, price, changePct float64) *m
 			USD: 0,
 		},
 		Volume24h: models.MultiCurrency{
-			USD: 0,
+			USD: volumeUSD,
 		},
+		Sources:   result.sources,
+		FetchedAt: now,
 	}
 
 	if !exists {
-		log.Printf("[%s] cache_miss: no cached supply data", coinID)
-		return data
+		if volumeUSD == 0 {
+			volume = lookupMiss
+		}
+		return data, lookupMiss, volume
 	}
 
 	// Use cached supply if valid
-	if snapshot.ValidSupply(now) {
+	supply = lookupExpired
+	if snapshot.ValidSupply(now, a.supplyTTL) {
 		if snapshot.Circulating > 0 {
 			data.MarketCap.USD = price * snapshot.Circulating
 		}
 		if snapshot.Full > 0 {
 			data.FullyDilutedValuation.USD = price * snapshot.Full
 		}
-		log.Printf("[%s] cache_hit supply: mc=%.2f fdv=%.2f", coinID, data.MarketCap.USD, data.FullyDilutedValuation.USD)
-	} else {
-		log.Printf("[%s] cache_expired supply", coinID)
+		data.SupplyAge = now.Sub(snapshot.UpdatedAt)
+		supply = lookupHit
 	}
 
-	// Use cached volume if valid
-	if snapshot.ValidVolume(now) {
+	// Live exchange volume wins; otherwise use cached volume if valid
+	switch {
+	case volumeUSD > 0:
+	case snapshot.ValidVolume(now, a.volumeTTL):
 		data.Volume24h.USD = snapshot.TotalVolumeUSD
-		log.Printf("[%s] cache_hit volume: %.2f", coinID, data.Volume24h.USD)
-	} else {
-		log.Printf("[%s] cache_expired volume", coinID)
+		data.VolumeAge = now.Sub(snapshot.UpdatedAt)
+		volume = lookupHit
+	default:
+		volume = lookupExpired
 	}
 
-	return data
+	return data, supply, volume
 }
@@ -0,0 +1,100 @@
+package market
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"scroll-rank-bot/internal/exchanges"
+	"scroll-rank-bot/internal/metrics"
+	"scroll-rank-bot/internal/models"
+)
+
+// tickerBatch fetches each exchange's tickers for all coins of an update cycle
+// that need fallback, with one request per exchange made on first use
+type tickerBatch struct {
+	ctx     context.Context     // The cycle's context, so a canceled hedge doesn't fail the batch for everyone
+	symbols map[string][]string // Symbols to fetch per provider name
+
+	mu    sync.Mutex
+	calls map[string]*batchCall
+}
+
+// batchCall is one exchange's batch request, shared by all coins of the cycle
+type batchCall struct {
+	once    sync.Once
+	tickers map[string]*exchanges.Ticker
+	err     error
+}
+
+// newTickerBatch collects the exchange symbols of coins that need fallback
+func (a *Aggregator) newTickerBatch(ctx context.Context, coins []models.Coin) *tickerBatch {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	symbols := make(map[string][]string)
+	for _, coin := range coins {
+		for _, name := range a.order[coin.ID] {
+			if symbol := a.symbols[coin.ID][name]; symbol != "" {
+				symbols[name] = append(symbols[name], symbol)
+			}
+		}
+	}
+	return &tickerBatch{ctx: ctx, symbols: symbols, calls: make(map[string]*batchCall)}
+}
+
+// lookup returns the symbol's ticker from the provider's batch, fetching the batch
+// if this is its first use. batched is false if the provider can't batch, in which
+// case the caller should request the symbol on its own; err is the batch request's
+// error. A symbol missing from a successful batch isn't listed by the exchange.
+func (b *tickerBatch) lookup(a *Aggregator, provider exchanges.Provider, symbol string) (ticker *exchanges.Ticker, batched bool, err error) {
+	if b == nil {
+		return nil, false, nil
+	}
+	batcher, canBatch := provider.(exchanges.BatchTicker)
+	if !canBatch {
+		return nil, false, nil
+	}
+
+	b.mu.Lock()
+	call, exists := b.calls[provider.Name()]
+	if !exists {
+		call = &batchCall{}
+		b.calls[provider.Name()] = call
+	}
+	b.mu.Unlock()
+
+	call.once.Do(func() {
+		call.tickers, call.err = a.fetchBatch(b.ctx, provider.Name(), batcher, b.symbols[provider.Name()])
+	})
+	if call.err != nil {
+		return nil, true, call.err
+	}
+	return call.tickers[symbol], true, nil
+}
+
+// fetchBatch requests the tickers of symbols from one exchange, retrying transient failures
+func (a *Aggregator) fetchBatch(ctx context.Context, name string, batcher exchanges.BatchTicker, symbols []string) (map[string]*exchanges.Ticker, error) {
+	start := time.Now()
+	var tickers map[string]*exchanges.Ticker
+	attempts, err := a.retry.Do(ctx, name, func(ctx context.Context) error {
+		var err error
+		tickers, err = batcher.GetTickers(ctx, symbols)
+		return err
+	})
+	latency := time.Since(start)
+	outcome := metrics.Outcome(err)
+	metrics.ExchangeDuration.WithLabelValues(name).Observe(latency.Seconds())
+	metrics.ExchangeRequests.WithLabelValues(name, outcome).Inc()
+	metrics.Retries.WithLabelValues(name).Add(float64(attempts - 1))
+	a.recordOutcome(name, outcome)
+
+	logger := slog.With("provider", name, "symbols", len(symbols), "attempts", attempts, "latency", latency)
+	if err != nil {
+		logger.Warn("Batch ticker fetch failed", "outcome", outcome, "error", err)
+		return nil, err
+	}
+	logger.Info("Fetched exchange tickers", "tickers", len(tickers))
+	return tickers, nil
+}
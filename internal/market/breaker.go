@@ -0,0 +1,123 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"scroll-rank-bot/internal/metrics"
+)
+
+// ErrCircuitOpen is returned instead of calling an upstream whose circuit is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// BreakerState is the state of a CircuitBreaker
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // Requests flow normally
+	BreakerHalfOpen                     // Cool-down elapsed, a single probe is allowed
+	BreakerOpen                         // Requests are rejected until the cool-down elapses
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerHalfOpen:
+		return "half-open"
+	case BreakerOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreaker stops calling an upstream after repeated failures. It opens after
+// threshold consecutive failures, rejects requests for cooldown, then lets a single
+// probe through: success closes it again, failure re-opens it.
+type CircuitBreaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewCircuitBreaker creates a closed breaker; name is used in logs and metrics
+func NewCircuitBreaker(name string, threshold int, cooldown time.Duration) *CircuitBreaker {
+	b := &CircuitBreaker{name: name, threshold: threshold, cooldown: cooldown}
+	metrics.BreakerState.WithLabelValues(name).Set(float64(BreakerClosed))
+	return b
+}
+
+// Allow reports whether a request may be sent now. Callers that get true must
+// report the outcome with Record.
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen {
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(BreakerHalfOpen)
+	}
+
+	if b.state == BreakerHalfOpen {
+		if b.probing {
+			return false
+		}
+		b.probing = true
+	}
+	return true
+}
+
+// Record reports the outcome of an allowed request. Cancellation (e.g. shutdown)
+// says nothing about the upstream and is not counted as a failure.
+func (b *CircuitBreaker) Record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+
+	if err == nil {
+		b.failures = 0
+		b.setState(BreakerClosed)
+		return
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.openedAt = time.Now()
+		b.setState(BreakerOpen)
+	}
+}
+
+// State returns the current state
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// setState transitions the breaker; the caller must hold b.mu
+func (b *CircuitBreaker) setState(state BreakerState) {
+	if b.state == state {
+		return
+	}
+
+	slog.Info("Circuit breaker state changed", "breaker", b.name, "from", b.state.String(), "to", state.String(), "failures", b.failures)
+	b.state = state
+	metrics.BreakerState.WithLabelValues(b.name).Set(float64(state))
+}
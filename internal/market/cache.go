@@ -0,0 +1,79 @@
+package market
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"scroll-rank-bot/internal/models"
+)
+
+// loadSupplyCache restores snapshots persisted by a previous run, dropping those
+// whose supply has already expired
+func (a *Aggregator) loadSupplyCache() error {
+	if a.cacheFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(a.cacheFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var snapshots map[string]models.SupplySnapshot
+	if err := json.Unmarshal(data, &snapshots); err != nil {
+		return fmt.Errorf("decode %s: %w", a.cacheFile, err)
+	}
+
+	now := time.Now()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for coinID, snapshot := range snapshots {
+		if !snapshot.ValidSupply(now, a.supplyTTL) {
+			continue
+		}
+		a.supplies[coinID] = snapshot
+	}
+
+	slog.Info("Loaded supply cache", "path", a.cacheFile, "coins", len(a.supplies), "expired", len(snapshots)-len(a.supplies))
+	return nil
+}
+
+// saveSupplyCache writes all snapshots to the cache file atomically
+func (a *Aggregator) saveSupplyCache() error {
+	if a.cacheFile == "" {
+		return nil
+	}
+
+	// Serialize writers so an older copy can never be renamed over a newer one
+	a.persistMu.Lock()
+	defer a.persistMu.Unlock()
+
+	data, err := json.MarshalIndent(a.SupplySnapshots(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(a.cacheFile), filepath.Base(a.cacheFile)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), a.cacheFile)
+}
@@ -0,0 +1,74 @@
+package market
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"scroll-rank-bot/internal/models"
+)
+
+func newCacheTestAggregator(cacheFile string) *Aggregator {
+	return NewAggregator(nil, Options{
+		HTTPTimeout: time.Second,
+		SupplyTTL:   models.SupplyTTL,
+		VolumeTTL:   models.VolumeTTL,
+		CacheFile:   cacheFile,
+	})
+}
+
+func TestSupplyCacheSurvivesRestart(t *testing.T) {
+	cacheFile := filepath.Join(t.TempDir(), "supply_cache.json")
+
+	before := newCacheTestAggregator(cacheFile)
+	before.updateSupplyCache("scroll", &models.CoinData{
+		Price:                 models.MultiCurrency{USD: 2},
+		MarketCap:             models.MultiCurrency{USD: 400},
+		FullyDilutedValuation: models.MultiCurrency{USD: 2000},
+		Volume24h:             models.MultiCurrency{USD: 50},
+	})
+	if err := before.saveSupplyCache(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A fresh process only has the file to go on
+	after := newCacheTestAggregator(cacheFile)
+	data := after.composeCoinData("scroll", priceResult{price: 3, sources: []string{"binance"}})
+
+	if data.MarketCap.USD != 600 {
+		t.Errorf("MarketCap = %v, want 600", data.MarketCap.USD)
+	}
+	if data.FullyDilutedValuation.USD != 3000 {
+		t.Errorf("FDV = %v, want 3000", data.FullyDilutedValuation.USD)
+	}
+	if data.Volume24h.USD != 50 {
+		t.Errorf("Volume24h = %v, want cached 50", data.Volume24h.USD)
+	}
+}
+
+func TestLoadSupplyCacheDropsExpired(t *testing.T) {
+	cacheFile := filepath.Join(t.TempDir(), "supply_cache.json")
+	now := time.Now()
+
+	snapshots := map[string]models.SupplySnapshot{
+		"fresh":   {Circulating: 100, Full: 1000, UpdatedAt: now.Add(-time.Hour)},
+		"expired": {Circulating: 100, Full: 1000, UpdatedAt: now.Add(-models.SupplyTTL - time.Hour)},
+	}
+	data, err := json.Marshal(snapshots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cacheFile, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := newCacheTestAggregator(cacheFile).SupplySnapshots()
+	if _, ok := loaded["fresh"]; !ok {
+		t.Error("fresh snapshot was dropped")
+	}
+	if _, ok := loaded["expired"]; ok {
+		t.Error("expired snapshot was loaded")
+	}
+}
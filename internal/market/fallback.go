@@ -0,0 +1,383 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"scroll-rank-bot/internal/exchanges"
+	"scroll-rank-bot/internal/metrics"
+	"scroll-rank-bot/internal/models"
+)
+
+// Fallback modes for pricing a coin from exchanges
+const (
+	ModeSequential = "sequential" // First exchange that answers, in the coin's exchange order
+	ModeHedged     = "hedged"     // Like sequential, but slow exchanges don't hold up the next
+	ModeConsensus  = "consensus"  // Median across all exchanges, outliers discarded
+)
+
+// candidate is an exchange that lists the coin under symbol
+type candidate struct {
+	provider exchanges.Provider
+	symbol   string
+	batch    *tickerBatch // Cycle-wide batch the ticker may already be in, nil if none
+}
+
+// exchangeQuote is the price, 24h change and 24h quote volume reported by one exchange
+type exchangeQuote struct {
+	provider  string
+	price     float64
+	changePct float64
+	volumeUSD float64
+}
+
+// priceResult is the price a fallback mode settled on, the summed 24h volume of
+// the exchanges behind it, and their names
+type priceResult struct {
+	price     float64
+	changePct float64
+	volumeUSD float64
+	sources   []string
+}
+
+// singleSource is the priceResult of one exchange's quote
+func singleSource(q exchangeQuote) priceResult {
+	return priceResult{price: q.price, changePct: q.changePct, volumeUSD: q.volumeUSD, sources: []string{q.provider}}
+}
+
+// fetchFromExchanges prices the coin from exchanges according to the fallback mode
+// and combines it with the cached supply. Fresh streamed tickers are used without
+// any request; otherwise tickers are taken from the cycle's batch where possible.
+func (a *Aggregator) fetchFromExchanges(ctx context.Context, coin models.Coin, tickers *tickerBatch) (*models.CoinData, error) {
+	if result, ok := a.streamQuote(coin.ID, time.Now()); ok {
+		slog.Debug("Priced from ticker stream", "coin", coin.ID, "price", result.price, "sources", result.sources)
+		return a.composeCoinData(coin.ID, result), nil
+	}
+
+	candidates, err := a.candidates(coin, tickers)
+	if err != nil {
+		return nil, err
+	}
+
+	var result priceResult
+	switch a.fallbackMode {
+	case ModeConsensus:
+		result, err = a.consensusQuote(ctx, coin.ID, candidates)
+	case ModeHedged:
+		result, err = a.hedgedQuote(ctx, coin.ID, candidates)
+	default:
+		result, err = a.sequentialQuote(ctx, coin.ID, candidates)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if a.fallbackMode != ModeConsensus {
+		result.volumeUSD = a.venueVolume(result, candidates)
+	}
+
+	return a.composeCoinData(coin.ID, result), nil
+}
+
+// venueVolume sums the 24h quote volume of every exchange listing the coin: the
+// answering exchange's own quote plus the other candidates' tickers from the
+// cycle's batch. Exchanges that can't batch, or whose batch failed, aren't asked
+// again just for their volume.
+func (a *Aggregator) venueVolume(result priceResult, candidates []candidate) float64 {
+	volumeUSD := result.volumeUSD
+	for _, c := range candidates {
+		if c.provider.Name() == result.sources[0] {
+			continue
+		}
+		if ticker, _, err := c.batch.lookup(a, c.provider, c.symbol); err == nil && ticker != nil {
+			volumeUSD += ticker.QuoteVolume24h
+		}
+	}
+	return volumeUSD
+}
+
+// candidates returns the exchanges that list the coin and aren't suspended, in the coin's exchange order
+func (a *Aggregator) candidates(coin models.Coin, tickers *tickerBatch) ([]candidate, error) {
+	a.mu.RLock()
+	exchangeSymbols, ok := a.symbols[coin.ID]
+	order := a.order[coin.ID]
+	a.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no exchange symbols configured for coin %s", coin.ID)
+	}
+
+	var candidates []candidate
+	var lastErr error
+
+	for _, name := range order {
+		symbol := exchangeSymbols[name]
+		provider, ok := a.providers[name]
+		if symbol == "" || !ok {
+			// This exchange doesn't support this coin
+			continue
+		}
+
+		// Skip exchanges that told us to back off
+		if until := provider.SuspendedUntil(); time.Now().Before(until) {
+			metrics.ExchangeRequests.WithLabelValues(provider.Name(), metrics.OutcomeSuspended).Inc()
+			slog.Debug("Provider suspended, skipping", "coin", coin.ID, "provider", provider.Name(), "symbol", symbol, "until", until)
+			lastErr = exchanges.NewProviderError(provider.Name(), symbol, fmt.Errorf("suspended until %s: %w", until.Format(time.RFC3339), exchanges.ErrRateLimited))
+			continue
+		}
+
+		candidates = append(candidates, candidate{provider: provider, symbol: symbol, batch: tickers})
+	}
+
+	if len(candidates) == 0 {
+		return nil, noQuoteError(coin.ID, lastErr)
+	}
+	return candidates, nil
+}
+
+// queryProvider fetches one exchange's quote from the cycle's batch, or with its
+// own request (retrying transient failures) if the exchange can't batch or its
+// batch failed transiently
+func (a *Aggregator) queryProvider(ctx context.Context, coinID string, c candidate) (exchangeQuote, error) {
+	provider := c.provider
+	logger := slog.With("coin", coinID, "provider", provider.Name(), "symbol", c.symbol)
+
+	ticker, batched, err := c.batch.lookup(a, provider, c.symbol)
+	switch {
+	case batched && err == nil && ticker == nil:
+		logger.Debug("Symbol not in exchange tickers")
+		return exchangeQuote{provider: provider.Name()}, exchanges.NewProviderError(provider.Name(), c.symbol, exchanges.ErrSymbolNotSupported)
+	case batched && err == nil:
+		logger.Debug("Exchange price from batch", "price", ticker.Price, "change_pct", ticker.ChangePct24h, "volume_usd", ticker.QuoteVolume24h)
+		return exchangeQuote{provider: provider.Name(), price: ticker.Price, changePct: ticker.ChangePct24h, volumeUSD: ticker.QuoteVolume24h}, nil
+	case batched:
+		// The batch may have just got the exchange to suspend us; don't follow up
+		// with a request per symbol
+		if until := provider.SuspendedUntil(); time.Now().Before(until) {
+			metrics.ExchangeRequests.WithLabelValues(provider.Name(), metrics.OutcomeSuspended).Inc()
+			logger.Debug("Provider suspended by batch request, skipping", "until", until)
+			return exchangeQuote{provider: provider.Name()}, exchanges.NewProviderError(provider.Name(), c.symbol, fmt.Errorf("suspended until %s: %w", until.Format(time.RFC3339), exchanges.ErrRateLimited))
+		}
+		// Per-symbol requests would fail the same way unless the failure was transient
+		if !exchanges.IsRetryable(err) {
+			return exchangeQuote{provider: provider.Name()}, err
+		}
+		logger.Debug("Batch failed transiently, requesting symbol on its own", "error", err)
+	}
+
+	start := time.Now()
+	attempts, err := a.retry.Do(ctx, provider.Name(), func(ctx context.Context) error {
+		var err error
+		ticker, err = provider.GetTicker(ctx, c.symbol)
+		return err
+	})
+	latency := time.Since(start)
+	outcome := metrics.Outcome(err)
+	metrics.ExchangeDuration.WithLabelValues(provider.Name()).Observe(latency.Seconds())
+	metrics.ExchangeRequests.WithLabelValues(provider.Name(), outcome).Inc()
+	a.recordOutcome(provider.Name(), outcome)
+	metrics.Retries.WithLabelValues(provider.Name()).Add(float64(attempts - 1))
+	logger = logger.With("attempts", attempts, "latency", latency)
+
+	switch {
+	case err == nil:
+		logger.Info("Fetched exchange price", "price", ticker.Price, "change_pct", ticker.ChangePct24h, "volume_usd", ticker.QuoteVolume24h)
+		return exchangeQuote{provider: provider.Name(), price: ticker.Price, changePct: ticker.ChangePct24h, volumeUSD: ticker.QuoteVolume24h}, nil
+	case errors.Is(err, exchanges.ErrSymbolNotSupported):
+		logger.Debug("Symbol not supported")
+	case ctx.Err() == nil:
+		logger.Warn("Exchange fetch failed", "outcome", outcome, "error", err)
+	}
+
+	return exchangeQuote{provider: provider.Name()}, err
+}
+
+// sequentialQuote returns the first successful quote, trying exchanges in order
+func (a *Aggregator) sequentialQuote(ctx context.Context, coinID string, candidates []candidate) (priceResult, error) {
+	var lastErr error
+
+	for _, c := range candidates {
+		quote, err := a.queryProvider(ctx, coinID, c)
+		if err == nil {
+			return singleSource(quote), nil
+		}
+
+		// The cycle deadline or shutdown cut the request off, no point trying the rest
+		if ctx.Err() != nil {
+			return priceResult{}, ctx.Err()
+		}
+		if !errors.Is(err, exchanges.ErrSymbolNotSupported) {
+			lastErr = err
+		}
+	}
+
+	return priceResult{}, noQuoteError(coinID, lastErr)
+}
+
+// hedgedQuote walks exchanges in order like sequentialQuote, but starts the next one
+// once the previous has been pending for hedgeDelay (or has failed). The first
+// successful quote wins and the requests still in flight are canceled.
+func (a *Aggregator) hedgedQuote(ctx context.Context, coinID string, candidates []candidate) (priceResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type outcome struct {
+		quote exchangeQuote
+		err   error
+	}
+	outcomes := make(chan outcome, len(candidates))
+
+	next, pending := 0, 0
+	var hedge <-chan time.Time
+	launch := func() {
+		c := candidates[next]
+		next++
+		pending++
+		go func() {
+			quote, err := a.queryProvider(ctx, coinID, c)
+			outcomes <- outcome{quote: quote, err: err}
+		}()
+		if next < len(candidates) {
+			hedge = time.After(a.hedgeDelay)
+		} else {
+			hedge = nil
+		}
+	}
+
+	var lastErr error
+	launch()
+	for pending > 0 {
+		select {
+		case <-hedge:
+			slog.Debug("Exchange slow, hedging with next", "coin", coinID, "provider", candidates[next].provider.Name())
+			launch()
+
+		case o := <-outcomes:
+			pending--
+			if o.err == nil {
+				return singleSource(o.quote), nil
+			}
+			if ctx.Err() != nil {
+				return priceResult{}, ctx.Err()
+			}
+			if !errors.Is(o.err, exchanges.ErrSymbolNotSupported) {
+				lastErr = o.err
+			}
+			// No point waiting out the delay once nothing is in flight
+			if pending == 0 && next < len(candidates) {
+				launch()
+			}
+		}
+	}
+
+	return priceResult{}, noQuoteError(coinID, lastErr)
+}
+
+// consensusQuote queries every exchange concurrently and combines their quotes
+func (a *Aggregator) consensusQuote(ctx context.Context, coinID string, candidates []candidate) (priceResult, error) {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		quotes  []exchangeQuote
+		lastErr error
+	)
+
+	for _, c := range candidates {
+		wg.Add(1)
+		go func(c candidate) {
+			defer wg.Done()
+			quote, err := a.queryProvider(ctx, coinID, c)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				quotes = append(quotes, quote)
+			} else if !errors.Is(err, exchanges.ErrSymbolNotSupported) {
+				lastErr = err
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	if len(quotes) == 0 {
+		if ctx.Err() != nil {
+			return priceResult{}, ctx.Err()
+		}
+		return priceResult{}, noQuoteError(coinID, lastErr)
+	}
+
+	result, outliers := consensus(quotes, a.maxDeviation)
+	for _, q := range outliers {
+		slog.Warn("Discarded outlier exchange price", "coin", coinID, "provider", q.provider, "price", q.price, "consensus_price", result.price)
+	}
+	slog.Info("Consensus exchange price", "coin", coinID, "price", result.price, "change_pct", result.changePct, "volume_usd", result.volumeUSD, "sources", result.sources)
+	return result, nil
+}
+
+// consensus takes the median price, drops quotes deviating from it by more than
+// maxDeviation (a fraction, e.g. 0.05 for 5%), and returns the median price and
+// 24h change and the summed volume of the remaining quotes along with the
+// discarded outliers
+func consensus(quotes []exchangeQuote, maxDeviation float64) (priceResult, []exchangeQuote) {
+	prices := make([]float64, 0, len(quotes))
+	for _, q := range quotes {
+		prices = append(prices, q.price)
+	}
+	reference := median(prices)
+
+	var kept, outliers []exchangeQuote
+	for _, q := range quotes {
+		if reference > 0 && abs(q.price/reference-1) > maxDeviation {
+			outliers = append(outliers, q)
+			continue
+		}
+		kept = append(kept, q)
+	}
+	// With two diverging clusters nothing is near the median; keep everything then
+	if len(kept) == 0 {
+		kept, outliers = quotes, nil
+	}
+
+	var keptPrices, keptChanges []float64
+	var volumeUSD float64
+	var sources []string
+	for _, q := range kept {
+		keptPrices = append(keptPrices, q.price)
+		keptChanges = append(keptChanges, q.changePct)
+		volumeUSD += q.volumeUSD
+		sources = append(sources, q.provider)
+	}
+	sort.Strings(sources)
+
+	return priceResult{price: median(keptPrices), changePct: median(keptChanges), volumeUSD: volumeUSD, sources: sources}, outliers
+}
+
+// median returns the median of values, which must not be empty
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[mid]
+	}
+	return (sorted[mid-1] + sorted[mid]) / 2
+}
+
+func abs(x float64) float64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// noQuoteError explains why no exchange could price the coin
+func noQuoteError(coinID string, lastErr error) error {
+	if lastErr != nil {
+		return fmt.Errorf("all exchanges failed, last error: %w", lastErr)
+	}
+	return fmt.Errorf("no supported exchange found for coin %s", coinID)
+}
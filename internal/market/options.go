@@ -0,0 +1,57 @@
+package market
+
+import (
+	"time"
+
+	"scroll-rank-bot/internal/config"
+	"scroll-rank-bot/internal/exchanges"
+	"scroll-rank-bot/internal/models"
+)
+
+// Options configures an Aggregator
+type Options struct {
+	HTTPTimeout time.Duration                     // Timeout for exchange requests
+	SupplyTTL   time.Duration                     // How long cached supply stays valid
+	VolumeTTL   time.Duration                     // How long cached volume stays valid
+	Symbols     map[string]models.ExchangeSymbols // Exchange symbols keyed by coin ID
+	Order       map[string][]string               // Exchanges to try keyed by coin ID, in order
+	CacheFile   string                            // Supply cache persistence file, empty disables it
+	LastGood    time.Duration                     // How long a coin's last good data is served after all sources fail
+
+	BreakerThreshold int           // Consecutive CoinGecko failures that open the circuit
+	BreakerCooldown  time.Duration // How long the circuit stays open before a probe
+
+	Retry exchanges.RetryPolicy // Retries of transient exchange failures
+
+	FallbackMode string        // How exchanges are combined: ModeSequential, ModeHedged or ModeConsensus
+	MaxDeviation float64       // Consensus mode drops prices this far (fraction) from the median
+	HedgeDelay   time.Duration // Hedged mode starts the next exchange after this long
+
+	Streaming    bool          // Subscribe to exchange ticker WebSockets
+	StreamMaxAge time.Duration // Streamed tickers older than this aren't used
+}
+
+// OptionsFromConfig builds aggregator options from the bot config
+func OptionsFromConfig(cfg *config.Config) Options {
+	return Options{
+		HTTPTimeout: cfg.HTTPTimeout,
+		SupplyTTL:   cfg.SupplyTTL,
+		VolumeTTL:   cfg.VolumeTTL,
+		Symbols:     cfg.Symbols(),
+		Order:       cfg.ExchangeOrder(),
+		CacheFile:   cfg.SupplyCacheFile,
+		LastGood:    cfg.LastGoodGrace,
+
+		BreakerThreshold: cfg.CoinGeckoBreaker.FailureThreshold,
+		BreakerCooldown:  cfg.CoinGeckoBreaker.Cooldown,
+
+		Retry: cfg.RetryPolicy(),
+
+		FallbackMode: cfg.Fallback.Mode,
+		MaxDeviation: cfg.Fallback.MaxDeviation,
+		HedgeDelay:   cfg.Fallback.HedgeDelay,
+
+		Streaming:    cfg.Streaming.Enabled,
+		StreamMaxAge: cfg.Streaming.MaxAge,
+	}
+}
@@ -0,0 +1,130 @@
+package market
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"scroll-rank-bot/internal/models"
+)
+
+// StartStreaming subscribes to the exchanges' ticker streams for every mapped
+// symbol until ctx is done. It does nothing unless streaming is enabled.
+func (a *Aggregator) StartStreaming(ctx context.Context) {
+	if a.streams == nil {
+		return
+	}
+	a.streams.Start(ctx, a.streamSymbols())
+}
+
+// streamSymbols returns the symbols of all coins keyed by provider name
+func (a *Aggregator) streamSymbols() map[string][]string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	symbols := make(map[string][]string)
+	seen := make(map[string]bool)
+	for coinID, order := range a.order {
+		for _, name := range order {
+			symbol := a.symbols[coinID][name]
+			if symbol == "" || seen[name+"/"+symbol] {
+				continue
+			}
+			seen[name+"/"+symbol] = true
+			symbols[name] = append(symbols[name], symbol)
+		}
+	}
+	return symbols
+}
+
+// streamQuote prices the coin from streamed tickers no older than streamMaxAge:
+// the first exchange in the coin's order with the volume of all of them, or the
+// consensus of all of them in consensus mode. It reports false if no exchange has
+// a fresh ticker.
+func (a *Aggregator) streamQuote(coinID string, now time.Time) (priceResult, bool) {
+	if a.streams == nil {
+		return priceResult{}, false
+	}
+
+	a.mu.RLock()
+	exchangeSymbols := a.symbols[coinID]
+	order := a.order[coinID]
+	a.mu.RUnlock()
+
+	var quotes []exchangeQuote
+	for _, name := range order {
+		symbol := exchangeSymbols[name]
+		if symbol == "" {
+			continue
+		}
+		q, ok := a.streams.Table().Get(name, symbol)
+		if !ok || now.Sub(q.At) > a.streamMaxAge {
+			continue
+		}
+		quotes = append(quotes, exchangeQuote{provider: name, price: q.Price, changePct: q.ChangePct24h, volumeUSD: q.QuoteVolume24h})
+	}
+
+	if len(quotes) == 0 {
+		return priceResult{}, false
+	}
+	if a.fallbackMode != ModeConsensus {
+		result := singleSource(quotes[0])
+		for _, q := range quotes[1:] {
+			result.volumeUSD += q.volumeUSD
+		}
+		return result, true
+	}
+
+	result, outliers := consensus(quotes, a.maxDeviation)
+	for _, q := range outliers {
+		slog.Warn("Discarded outlier streamed price", "coin", coinID, "provider", q.provider, "price", q.price, "consensus_price", result.price)
+	}
+	return result, true
+}
+
+// Reprice returns a copy of results with every coin that has fresh streamed
+// tickers priced from them, re-sorted by FDV. Without streaming it returns results
+// as they are. It has no metric or log side effects, since /rank calls it on every
+// request.
+//
+// CoinGecko rows keep CoinGecko's supply and all-venue volume: only the price, the
+// 24h change and the MC/FDV derived from the price follow the stream. Other rows
+// are rebuilt from the stream and the cached supply like the exchange fallback.
+func (a *Aggregator) Reprice(results []models.CoinResult) []models.CoinResult {
+	if a.streams == nil {
+		return results
+	}
+
+	now := time.Now()
+	repriced := make([]models.CoinResult, len(results))
+	for i, result := range results {
+		if quote, ok := a.streamQuote(result.Coin.ID, now); ok {
+			if result.Data != nil && !result.Data.Stale && !result.Data.FromExchanges() {
+				result.Data = repriceCoinGecko(result.Data, quote, now)
+			} else {
+				result.Data, _, _ = a.buildCoinData(result.Coin.ID, quote, now)
+			}
+		}
+		repriced[i] = result
+	}
+
+	sortByFDV(repriced)
+	return repriced
+}
+
+// repriceCoinGecko returns a copy of CoinGecko data at the streamed price, scaling
+// MC and FDV by the price change so the implied supply stays CoinGecko's. The
+// stream's exchanges are added to the sources after CoinGecko.
+func repriceCoinGecko(data *models.CoinData, quote priceResult, now time.Time) *models.CoinData {
+	repriced := *data
+	if data.Price.USD > 0 {
+		ratio := quote.price / data.Price.USD
+		repriced.MarketCap.USD = data.MarketCap.USD * ratio
+		repriced.FullyDilutedValuation.USD = data.FullyDilutedValuation.USD * ratio
+	}
+	repriced.Price.USD = quote.price
+	repriced.PriceChangePercentage24h = quote.changePct
+	repriced.Sources = append([]string{models.SourceCoinGecko}, quote.sources...)
+	repriced.FetchedAt = now
+	return &repriced
+}
@@ -0,0 +1,144 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"scroll-rank-bot/internal/exchanges"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "scroll_rank_bot"
+
+// Outcome label values
+const (
+	OutcomeSuccess      = "success"
+	OutcomeRateLimited  = "rate_limited"
+	OutcomeSuspended    = "suspended"
+	OutcomeNotSupported = "not_supported"
+	OutcomeMaintenance  = "maintenance"
+	OutcomeBadRequest   = "bad_request"
+	OutcomeServerError  = "server_error"
+	OutcomeCanceled     = "canceled"
+	OutcomeError        = "error"
+)
+
+var (
+	CoinGeckoRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "coingecko_requests_total",
+		Help:      "CoinGecko requests by outcome.",
+	}, []string{"outcome"})
+
+	CoinGeckoDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "coingecko_request_duration_seconds",
+		Help:      "CoinGecko request latency.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	ExchangeRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "exchange_requests_total",
+		Help:      "Exchange ticker requests by provider and outcome.",
+	}, []string{"provider", "outcome"})
+
+	ExchangeDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "exchange_request_duration_seconds",
+		Help:      "Exchange ticker request latency by provider.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"provider"})
+
+	Retries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_retries_total",
+		Help:      "Retried upstream requests (attempts beyond the first) by upstream.",
+	}, []string{"upstream"})
+
+	DataSource = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "coin_data_source_total",
+		Help:      "Coin data fetches by the source that served them (coingecko, exchange, last_good or none).",
+	}, []string{"source"})
+
+	SupplyCacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "supply_cache_lookups_total",
+		Help:      "Supply cache lookups during exchange fallback by field (supply, volume) and result (hit, miss, expired).",
+	}, []string{"field", "result"})
+
+	StreamConnected = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ticker_stream_connected",
+		Help:      "Whether the exchange ticker WebSocket is connected, by provider.",
+	}, []string{"provider"})
+
+	StreamReconnects = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ticker_stream_reconnects_total",
+		Help:      "Exchange ticker WebSocket reconnects by provider.",
+	}, []string{"provider"})
+
+	GasRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "gas_rpc_requests_total",
+		Help:      "eth_gasPrice RPC calls by network and outcome.",
+	}, []string{"network", "outcome"})
+
+	GasDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "gas_rpc_duration_seconds",
+		Help:      "eth_gasPrice RPC latency by network.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"network"})
+
+	BreakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "circuit_breaker_state",
+		Help:      "Circuit breaker state by upstream: 0 closed, 1 half-open, 2 open.",
+	}, []string{"breaker"})
+
+	UpdateCycleDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "update_cycle_duration_seconds",
+		Help:      "Duration of a full coin data update cycle.",
+		Buckets:   []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120},
+	})
+
+	UpdateCycleUnavailable = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "update_cycle_unavailable_coins",
+		Help:      "Coins left without data by the last update cycle.",
+	})
+)
+
+// Outcome maps an error to an outcome label
+func Outcome(err error) string {
+	switch {
+	case err == nil:
+		return OutcomeSuccess
+	case errors.Is(err, exchanges.ErrRateLimited):
+		return OutcomeRateLimited
+	case errors.Is(err, exchanges.ErrSymbolNotSupported):
+		return OutcomeNotSupported
+	case errors.Is(err, exchanges.ErrMaintenance):
+		return OutcomeMaintenance
+	case errors.Is(err, exchanges.ErrBadRequest):
+		return OutcomeBadRequest
+	case errors.Is(err, exchanges.ErrServer):
+		return OutcomeServerError
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return OutcomeCanceled
+	default:
+		return OutcomeError
+	}
+}
+
+// Since returns the seconds elapsed since start, for histogram observations
+func Since(start time.Time) float64 {
+	return time.Since(start).Seconds()
+}
@@ -4,24 +4,24 @@ import "time"
 
 // SupplySnapshot holds cached supply and volume data for a coin
 type SupplySnapshot struct {
-	Circulating    float64   // Circulating supply
-	Full           float64   // Full/max supply
-	TotalVolumeUSD float64   // 24h volume in USD
-	UpdatedAt      time.Time // When this snapshot was created
+	Circulating    float64   `json:"circulating"`      // Circulating supply
+	Full           float64   `json:"full"`             // Full/max supply
+	TotalVolumeUSD float64   `json:"total_volume_usd"` // 24h volume in USD
+	UpdatedAt      time.Time `json:"updated_at"`       // When this snapshot was created
 }
 
-// TTL constants for cache expiration
+// Default TTLs for cache expiration
 const (
 	SupplyTTL = 24 * time.Hour   // Circulating/Full supply cache lifetime
 	VolumeTTL = 30 * time.Minute // Volume cache lifetime
 )
 
 // ValidSupply checks if the supply data (Circulating/Full) is still valid
-func (s *SupplySnapshot) ValidSupply(now time.Time) bool {
-	return now.Sub(s.UpdatedAt) < SupplyTTL
+func (s *SupplySnapshot) ValidSupply(now time.Time, ttl time.Duration) bool {
+	return now.Sub(s.UpdatedAt) < ttl
 }
 
 // ValidVolume checks if the volume data is still valid
-func (s *SupplySnapshot) ValidVolume(now time.Time) bool {
-	return now.Sub(s.UpdatedAt) < VolumeTTL
+func (s *SupplySnapshot) ValidVolume(now time.Time, ttl time.Duration) bool {
+	return now.Sub(s.UpdatedAt) < ttl
 }
@@ -2,92 +2,82 @@ package models
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"os"
 )
 
-// ExchangeSymbols holds trading pair symbols for a coin across different exchanges
-type ExchangeSymbols struct {
-	Binance string `json:"binance"`
-	OKX     string `json:"okx"`
-	Bybit   string `json:"bybit"`
-	Bitget  string `json:"bitget"`
-}
+// ExchangeSymbols holds a coin's trading pair symbol on each exchange, keyed by
+// exchange (provider) name
+type ExchangeSymbols map[string]string
 
-// Symbols maps coin IDs to their exchange-specific trading symbols
-// Key is models.Coin.ID
+// Symbols holds the default exchange-specific trading symbols used when the
+// config doesn't override them. Key is models.Coin.ID
 var Symbols = map[string]ExchangeSymbols{
 	"starknet": {
-		Binance: "STRKUSDT",
-		OKX:     "STRK-USDT",
-		Bybit:   "STRKUSDT",
-		Bitget:  "STRKUSDT",
+		"binance": "STRKUSDT",
+		"okx":     "STRK-USDT",
+		"bybit":   "STRKUSDT",
+		"bitget":  "STRKUSDT",
 	},
 	"zksync": {
-		Binance: "ZKUSDT",
-		OKX:     "ZK-USDT",
-		Bybit:   "ZKUSDT",
-		Bitget:  "ZKUSDT",
+		"binance": "ZKUSDT",
+		"okx":     "ZK-USDT",
+		"bybit":   "ZKUSDT",
+		"bitget":  "ZKUSDT",
 	},
 	"taiko": {
-		Binance: "TAIKOUSDT",
-		OKX:     "TAIKO-USDT",
-		Bybit:   "TAIKOUSDT",
-		Bitget:  "TAIKOUSDT",
+		"binance": "TAIKOUSDT",
+		"okx":     "TAIKO-USDT",
+		"bybit":   "TAIKOUSDT",
+		"bitget":  "TAIKOUSDT",
 	},
 	"scroll": {
-		Binance: "SCRUSDT",
-		OKX:     "SCR-USDT",
-		Bybit:   "SCRUSDT",
-		Bitget:  "SCRUSDT",
+		"binance": "SCRUSDT",
+		"okx":     "SCR-USDT",
+		"bybit":   "SCRUSDT",
+		"bitget":  "SCRUSDT",
 	},
 	"movement": {
-		Binance: "MOVEUSDT",
-		OKX:     "MOVE-USDT",
-		Bybit:   "MOVEUSDT",
-		Bitget:  "MOVEUSDT",
+		"binance": "MOVEUSDT",
+		"okx":     "MOVE-USDT",
+		"bybit":   "MOVEUSDT",
+		"bitget":  "MOVEUSDT",
 	},
 	"polyhedra-network": {
-		Binance: "ZKJUSDT",
-		OKX:     "ZKJ-USDT",
-		Bybit:   "ZKJUSDT",
-		Bitget:  "ZKJUSDT",
+		"binance": "ZKJUSDT",
+		"okx":     "ZKJ-USDT",
+		"bybit":   "ZKJUSDT",
+		"bitget":  "ZKJUSDT",
 	},
 	"linea": {
-		Binance: "LINEAUSDT",
-		OKX:     "LINEA-USDT",
-		Bybit:   "LINEAUSDT",
-		Bitget:  "LINEAUSDT",
+		"binance": "LINEAUSDT",
+		"okx":     "LINEA-USDT",
+		"bybit":   "LINEAUSDT",
+		"bitget":  "LINEAUSDT",
 	},
 }
 
-// LoadSymbolsFromJSON loads symbol mappings from a JSON file and merges with defaults
-// If the file doesn't exist or is empty, uses default mappings
-func LoadSymbolsFromJSON(filePath string) error {
+// LoadSymbolsFromJSON reads symbol mappings from a JSON file
+// If the path is empty or the file doesn't exist, it returns no mappings
+func LoadSymbolsFromJSON(filePath string) (map[string]ExchangeSymbols, error) {
 	if filePath == "" {
-		log.Println("No custom symbols file specified, using defaults")
-		return nil
+		return nil, nil
 	}
 
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			log.Printf("Symbols file %s not found, using defaults", filePath)
-			return nil
+			slog.Info("Symbols file not found, using configured symbols", "path", filePath)
+			return nil, nil
 		}
-		return err
+		return nil, err
 	}
 
 	var customSymbols map[string]ExchangeSymbols
 	if err := json.Unmarshal(data, &customSymbols); err != nil {
-		return err
-	}
-
-	// Merge custom symbols with defaults
-	for coinID, exchangeSymbols := range customSymbols {
-		Symbols[coinID] = exchangeSymbols
+		return nil, err
 	}
 
-	log.Printf("Loaded custom symbols from %s", filePath)
-	return nil
+	slog.Info("Loaded custom symbols", "path", filePath)
+	return customSymbols, nil
 }
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type Coin struct {
 	Name string
 	ID   string
@@ -15,10 +17,27 @@ type CoinData struct {
 	MarketCap                MultiCurrency `json:"market_cap"`
 	FullyDilutedValuation    MultiCurrency `json:"fully_diluted_valuation"`
 	Volume24h                MultiCurrency `json:"total_volume"`
+
+	// Provenance, filled in by the aggregator rather than decoded from CoinGecko
+	Sources   []string      `json:"-"` // SourceCoinGecko (then any streams repricing it) or the exchanges that priced the coin
+	FetchedAt time.Time     `json:"-"` // When the price was fetched
+	SupplyAge time.Duration `json:"-"` // Age of the cached supply behind MC/FDV, zero if live
+	VolumeAge time.Duration `json:"-"` // Age of the cached volume, zero if live
+	Stale     bool          `json:"-"` // Last good data served after every source failed
 }
 
-type CoinGeckoResponse struct {
-	MarketData CoinData `json:"market_data"`
+// SourceCoinGecko is the CoinData source for coins priced by CoinGecko
+const SourceCoinGecko = "coingecko"
+
+// FromExchanges reports whether the data was priced by exchanges rather than CoinGecko
+func (d *CoinData) FromExchanges() bool {
+	return len(d.Sources) > 0 && d.Sources[0] != SourceCoinGecko
+}
+
+// CoinResult pairs a tracked coin with its fetched data (nil if unavailable)
+type CoinResult struct {
+	Coin Coin
+	Data *CoinData
 }
 
 type RPCRequest struct {
@@ -27,3 +46,11 @@ type RPCRequest struct {
 	Params  []string `json:"params"`
 	ID      int      `json:"id"`
 }
+
+// GasNetwork describes a chain whose gas price is reported by /gas_price
+type GasNetwork struct {
+	ID   string `yaml:"id"`
+	Name string `yaml:"name"`
+	Icon string `yaml:"icon"`
+	RPC  string `yaml:"rpc"`
+}
@@ -0,0 +1,113 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"scroll-rank-bot/internal/models"
+)
+
+// CoinRow is the machine-readable form of a ranked coin
+type CoinRow struct {
+	Rank                 int        `json:"rank"`
+	ID                   string     `json:"id"`
+	Name                 string     `json:"name"`
+	Available            bool       `json:"available"`
+	PriceUSD             float64    `json:"price_usd"`
+	PriceChange24hPct    float64    `json:"price_change_24h_pct"`
+	Volume24hUSD         float64    `json:"volume_24h_usd"`
+	MarketCapUSD         float64    `json:"market_cap_usd"`
+	FullyDilutedValueUSD float64    `json:"fdv_usd"`
+	Stale                bool       `json:"stale,omitempty"`
+	Sources              []string   `json:"sources,omitempty"`
+	FetchedAt            *time.Time `json:"fetched_at,omitempty"`
+	SupplyAgeSeconds     float64    `json:"supply_age_seconds,omitempty"`
+	VolumeAgeSeconds     float64    `json:"volume_age_seconds,omitempty"`
+}
+
+// NetworkRow is the machine-readable form of a network's gas price
+type NetworkRow struct {
+	ID      string  `json:"id"`
+	Name    string  `json:"name"`
+	GasGwei float64 `json:"gas_gwei"`
+}
+
+// Rows converts ranked results into rows, keeping their order
+func Rows(results []models.CoinResult) []CoinRow {
+	rows := make([]CoinRow, 0, len(results))
+	for i, item := range results {
+		row := CoinRow{Rank: i + 1, ID: item.Coin.ID, Name: item.Coin.Name}
+		if item.Data != nil {
+			row.Available = true
+			row.PriceUSD = item.Data.Price.USD
+			row.PriceChange24hPct = item.Data.PriceChangePercentage24h
+			row.Volume24hUSD = item.Data.Volume24h.USD
+			row.MarketCapUSD = item.Data.MarketCap.USD
+			row.FullyDilutedValueUSD = item.Data.FullyDilutedValuation.USD
+			row.Stale = item.Data.Stale
+			row.Sources = item.Data.Sources
+			if !item.Data.FetchedAt.IsZero() {
+				fetchedAt := item.Data.FetchedAt.UTC()
+				row.FetchedAt = &fetchedAt
+			}
+			row.SupplyAgeSeconds = item.Data.SupplyAge.Seconds()
+			row.VolumeAgeSeconds = item.Data.VolumeAge.Seconds()
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// WriteRankingsJSON writes the rankings as a single JSON document
+func WriteRankingsJSON(w io.Writer, results []models.CoinResult, now time.Time) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		UpdatedAt time.Time `json:"updated_at"`
+		Coins     []CoinRow `json:"coins"`
+	}{UpdatedAt: now.UTC(), Coins: Rows(results)})
+}
+
+// WriteRankingsCSV writes the rankings as CSV with a header row.
+// Numeric columns are left empty for coins whose data is unavailable.
+func WriteRankingsCSV(w io.Writer, results []models.CoinResult) error {
+	cw := csv.NewWriter(w)
+	header := []string{"rank", "id", "name", "price_usd", "price_change_24h_pct", "volume_24h_usd", "market_cap_usd", "fdv_usd"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, row := range Rows(results) {
+		record := []string{strconv.Itoa(row.Rank), row.ID, row.Name, "", "", "", "", ""}
+		if row.Available {
+			values := []float64{row.PriceUSD, row.PriceChange24hPct, row.Volume24hUSD, row.MarketCapUSD, row.FullyDilutedValueUSD}
+			for i, v := range values {
+				record[3+i] = strconv.FormatFloat(v, 'f', -1, 64)
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteGasPricesJSON writes gas prices in Gwei as a single JSON document
+func WriteGasPricesJSON(w io.Writer, networks []models.GasNetwork, prices map[string]float64, now time.Time) error {
+	rows := make([]NetworkRow, 0, len(networks))
+	for _, network := range networks {
+		rows = append(rows, NetworkRow{ID: network.ID, Name: network.Name, GasGwei: prices[network.ID]})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		UpdatedAt time.Time    `json:"updated_at"`
+		Networks  []NetworkRow `json:"networks"`
+	}{UpdatedAt: now.UTC(), Networks: rows})
+}
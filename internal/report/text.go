@@ -0,0 +1,145 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"scroll-rank-bot/internal/exchanges"
+	"scroll-rank-bot/internal/models"
+)
+
+// Rankings renders the /rank message for results already sorted by FDV
+func Rankings(results []models.CoinResult, now time.Time) string {
+	var messages []string
+
+	// Add a header line with emojis
+	header := "🏆 L2 RANKINGS BY FDV 🏆"
+
+	for i, item := range results {
+		// Add ranking number for each coin
+		messages = append(messages, formatSingleCoin(i+1, item.Coin, item.Data, now))
+	}
+
+	// More compact date format
+	timestamp := now.UTC().Format("2006-01-02 15:04 UTC")
+
+	return fmt.Sprintf("%s\n\n\n%s\n\n\n📊 Updated: %s",
+		header,
+		strings.Join(messages, "\n\n"),
+		timestamp)
+}
+
+func formatSingleCoin(rank int, coin models.Coin, data *models.CoinData, now time.Time) string {
+	if data == nil {
+		return fmt.Sprintf("#%d %s: Data unavailable", rank, coin.ID)
+	}
+
+	// Determine emoji based on rank
+	rankEmoji := ""
+	switch rank {
+	case 1:
+		rankEmoji = "🥇"
+	case 2:
+		rankEmoji = "🥈"
+	case 3:
+		rankEmoji = "🥉"
+	default:
+		rankEmoji = "▫️"
+	}
+
+	// Set price change arrow and color indicator (using emoji)
+	priceChangeIndicator := "➖"
+	if data.PriceChangePercentage24h > 0 {
+		priceChangeIndicator = "🟢"
+	} else if data.PriceChangePercentage24h < 0 {
+		priceChangeIndicator = "🔴"
+	}
+
+	// More compact single-line format per coin
+	return fmt.Sprintf(`%s #%d %s | 💰 %s (%s%.2f%%) | 📈 Vol: %s | 💎 MC: %s | 🌐 FDV: %s%s`,
+		rankEmoji,
+		rank,
+		coin.Name,
+		formatPrice(data.Price.USD),
+		priceChangeIndicator,
+		data.PriceChangePercentage24h,
+		formatValue(data.Volume24h.USD),
+		formatValue(data.MarketCap.USD),
+		formatValue(data.FullyDilutedValuation.USD),
+		formatProvenance(data, now))
+}
+
+// formatProvenance marks rows that aren't fresh CoinGecko data: last good data
+// kept after every source failed, and rows priced from exchanges with how old the
+// cached supply and volume are, e.g. " | ⚠️ via OKX, supply 6h old"
+func formatProvenance(data *models.CoinData, now time.Time) string {
+	var parts []string
+	if data.Stale {
+		parts = append(parts, "last good "+formatAge(now.Sub(data.FetchedAt))+" ago")
+	}
+
+	if data.FromExchanges() {
+		names := make([]string, 0, len(data.Sources))
+		for _, source := range data.Sources {
+			names = append(names, exchanges.DisplayName(source))
+		}
+		parts = append(parts, "via "+strings.Join(names, "/"))
+
+		if data.SupplyAge > 0 {
+			parts = append(parts, "supply "+formatAge(data.SupplyAge)+" old")
+		}
+		if data.VolumeAge > 0 {
+			parts = append(parts, "vol "+formatAge(data.VolumeAge)+" old")
+		}
+	}
+
+	if len(parts) == 0 {
+		return ""
+	}
+	return " | ⚠️ " + strings.Join(parts, ", ")
+}
+
+// formatAge renders a cache age coarsely: minutes under an hour, then hours, then days
+func formatAge(age time.Duration) string {
+	switch {
+	case age < time.Hour:
+		return fmt.Sprintf("%dm", int(age.Minutes()))
+	case age < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(age.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(age.Hours()/24))
+	}
+}
+
+// GasPrices renders the /gas_price message; prices are in Gwei keyed by network ID
+func GasPrices(networks []models.GasNetwork, prices map[string]float64, now time.Time) string {
+	var lines []string
+	for _, network := range networks {
+		lines = append(lines, fmt.Sprintf("%s %s: %.2f", network.Icon, network.Name, prices[network.ID]))
+	}
+
+	return fmt.Sprintf("🔄 Current Gas Prices (Gwei):\n\n%s\n\nUpdated: %s UTC",
+		strings.Join(lines, "\n"),
+		now.UTC().Format("2006-01-02 15:04:05"))
+}
+
+func formatValue(value float64) string {
+	if value == 0 {
+		return "N/A"
+	}
+	if value >= 1e9 {
+		return fmt.Sprintf("%.2f B", value/1e9)
+	}
+	if value >= 1e6 {
+		return fmt.Sprintf("%.2f M", value/1e6)
+	}
+	return fmt.Sprintf("%.2f", value)
+}
+
+func formatPrice(price float64) string {
+	if price == 0 {
+		return "N/A"
+	}
+	return fmt.Sprintf("$%.4f", price)
+}
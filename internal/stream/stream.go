@@ -0,0 +1,277 @@
+package stream
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"scroll-rank-bot/internal/exchanges"
+	"scroll-rank-bot/internal/metrics"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// readTimeout drops a connection that delivered nothing (not even a heartbeat
+	// reply or ping) for this long
+	readTimeout = time.Minute
+	// writeTimeout bounds subscribe, heartbeat and pong writes
+	writeTimeout = 10 * time.Second
+
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+)
+
+// Quote is the latest streamed ticker of a symbol and when it arrived
+type Quote struct {
+	exchanges.Ticker
+	At time.Time
+}
+
+// Table holds the latest streamed ticker per exchange and symbol
+type Table struct {
+	mu     sync.RWMutex
+	quotes map[string]map[string]Quote
+}
+
+// NewTable creates an empty price table
+func NewTable() *Table {
+	return &Table{quotes: make(map[string]map[string]Quote)}
+}
+
+// Get returns the latest ticker of symbol streamed by the named exchange
+func (t *Table) Get(exchange, symbol string) (Quote, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	q, ok := t.quotes[exchange][symbol]
+	return q, ok
+}
+
+// set records a streamed ticker
+func (t *Table) set(exchange, symbol string, ticker *exchanges.Ticker, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.quotes[exchange] == nil {
+		t.quotes[exchange] = make(map[string]Quote)
+	}
+	t.quotes[exchange][symbol] = Quote{Ticker: *ticker, At: at}
+}
+
+// Manager keeps one WebSocket per exchange subscribed to the ticker streams of the
+// configured symbols, recording every ticker in its Table
+type Manager struct {
+	table     *Table
+	providers map[string]exchanges.Provider
+	dialer    *websocket.Dialer
+
+	mu      sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	symbols map[string][]string
+}
+
+// NewManager creates a stream manager for the providers that implement exchanges.Streamer
+func NewManager(providers map[string]exchanges.Provider) *Manager {
+	return &Manager{
+		table:     NewTable(),
+		providers: providers,
+		dialer: &websocket.Dialer{
+			Proxy:            http.ProxyFromEnvironment,
+			HandshakeTimeout: writeTimeout,
+		},
+	}
+}
+
+// Table returns the table of streamed tickers
+func (m *Manager) Table() *Table {
+	return m.table
+}
+
+// Start connects to the streams of symbols (keyed by provider name) and keeps them
+// connected until ctx is done
+func (m *Manager) Start(ctx context.Context, symbols map[string][]string) {
+	m.mu.Lock()
+	m.ctx = ctx
+	m.mu.Unlock()
+	m.SetSymbols(symbols)
+}
+
+// SetSymbols replaces the subscribed symbols, reconnecting the streams if they
+// changed. It just records them if the manager hasn't been started.
+func (m *Manager) SetSymbols(symbols map[string][]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.ctx == nil {
+		m.symbols = symbols
+		return
+	}
+	if m.cancel != nil {
+		if sameSymbols(m.symbols, symbols) {
+			return
+		}
+		// Restart all connections; reloads are rare and a reconnect costs a second
+		m.cancel()
+		m.wg.Wait()
+	}
+	m.symbols = symbols
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.cancel = cancel
+
+	for name, list := range symbols {
+		provider, ok := m.providers[name]
+		if !ok || len(list) == 0 {
+			continue
+		}
+		streamer, ok := provider.(exchanges.Streamer)
+		if !ok {
+			continue
+		}
+		m.wg.Add(1)
+		go func(name string, stream exchanges.Stream) {
+			defer m.wg.Done()
+			m.run(ctx, name, stream)
+		}(name, streamer.TickerStream(list))
+	}
+}
+
+// run keeps one exchange's stream connected until ctx is done, reconnecting with
+// exponential backoff that resets once a connection delivers tickers
+func (m *Manager) run(ctx context.Context, name string, stream exchanges.Stream) {
+	logger := slog.With("provider", name)
+	backoff := minBackoff
+
+	for {
+		received, err := m.session(ctx, name, stream)
+		metrics.StreamConnected.WithLabelValues(name).Set(0)
+		if ctx.Err() != nil {
+			return
+		}
+		if received {
+			backoff = minBackoff
+		}
+
+		metrics.StreamReconnects.WithLabelValues(name).Inc()
+		logger.Warn("Ticker stream disconnected, reconnecting", "in", backoff, "error", err)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if !received {
+			backoff = min(backoff*2, maxBackoff)
+		}
+	}
+}
+
+// session runs one connection until it fails or ctx is done. received reports
+// whether any ticker arrived over it.
+func (m *Manager) session(ctx context.Context, name string, stream exchanges.Stream) (received bool, err error) {
+	conn, _, err := m.dialer.DialContext(ctx, stream.URL, nil)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	// Closing the connection is the only way to interrupt a blocked read
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	for _, msg := range stream.Subscribe {
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if err := conn.WriteJSON(msg); err != nil {
+			return false, err
+		}
+	}
+
+	conn.SetReadDeadline(time.Now().Add(readTimeout))
+	conn.SetPingHandler(func(data string) error {
+		conn.SetReadDeadline(time.Now().Add(readTimeout))
+		err := conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(writeTimeout))
+		if err == websocket.ErrCloseSent {
+			return nil
+		}
+		return err
+	})
+
+	// The heartbeat is the only writer from here on, so writes never overlap
+	if stream.Ping != nil {
+		go heartbeat(conn, stream, done)
+	}
+
+	metrics.StreamConnected.WithLabelValues(name).Set(1)
+	slog.Info("Ticker stream connected", "provider", name)
+
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return received, err
+		}
+		conn.SetReadDeadline(time.Now().Add(readTimeout))
+
+		now := time.Now()
+		for symbol, ticker := range stream.Parse(msg) {
+			m.table.set(name, symbol, ticker, now)
+			received = true
+		}
+	}
+}
+
+// heartbeat sends the stream's text ping every PingInterval until done is closed.
+// A failed write closes the connection so the read loop reconnects.
+func heartbeat(conn *websocket.Conn, stream exchanges.Stream, done <-chan struct{}) {
+	ticker := time.NewTicker(stream.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := conn.WriteMessage(websocket.TextMessage, stream.Ping); err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// sameSymbols reports whether a and b subscribe each provider to the same symbols
+func sameSymbols(a, b map[string][]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, list := range a {
+		other, ok := b[name]
+		if !ok || len(list) != len(other) {
+			return false
+		}
+		x := append([]string(nil), list...)
+		y := append([]string(nil), other...)
+		sort.Strings(x)
+		sort.Strings(y)
+		for i := range x {
+			if x[i] != y[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,105 @@
+package stream
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"scroll-rank-bot/internal/exchanges"
+
+	"github.com/gorilla/websocket"
+)
+
+// localOKX is the real OKX provider with its stream pointed at a local server
+type localOKX struct {
+	*exchanges.OKXProvider
+	url string
+}
+
+func (p localOKX) TickerStream(symbols []string) exchanges.Stream {
+	stream := p.OKXProvider.TickerStream(symbols)
+	stream.URL = p.url
+	return stream
+}
+
+func TestManagerSubscribesAndReconnects(t *testing.T) {
+	prices := []string{"0.7000", "0.7500"}
+	subscribes := make(chan string, len(prices))
+
+	upgrader := websocket.Upgrader{}
+	var connections atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		n := int(connections.Add(1))
+		if n > len(prices) {
+			return
+		}
+		price := prices[n-1]
+
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		subscribes <- string(msg)
+
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"event":"subscribe","arg":{"channel":"tickers","instId":"SCR-USDT"}}`))
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"arg":{"channel":"tickers","instId":"SCR-USDT"},"data":[{"instId":"SCR-USDT","last":"`+price+`","open24h":"0.7000","volCcy24h":"125000"}]}`))
+
+		if n == 1 {
+			// Drop the first connection once the ticker is out, like an exchange restart
+			time.Sleep(50 * time.Millisecond)
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "restart"))
+			return
+		}
+		// Keep the second connection open until the client leaves
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	provider := localOKX{OKXProvider: exchanges.NewOKXProvider(time.Second), url: "ws" + strings.TrimPrefix(server.URL, "http")}
+	manager := NewManager(map[string]exchanges.Provider{"okx": provider})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	manager.Start(ctx, map[string][]string{"okx": {"SCR-USDT"}})
+
+	for i := range prices {
+		select {
+		case msg := <-subscribes:
+			if !strings.Contains(msg, `"op":"subscribe"`) || !strings.Contains(msg, `"instId":"SCR-USDT"`) {
+				t.Fatalf("subscribe %d = %s", i, msg)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("subscribe %d not received", i)
+		}
+	}
+
+	// The resubscribed connection's ticker replaces the first one
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		quote, ok := manager.Table().Get("okx", "SCR-USDT")
+		if ok && quote.Price == 0.75 {
+			if quote.QuoteVolume24h != 125000 {
+				t.Errorf("QuoteVolume24h = %v, want 125000", quote.QuoteVolume24h)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("quote after reconnect = %+v (found %v), want price 0.75", quote, ok)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}